@@ -0,0 +1,201 @@
+package gosmartis
+
+import "context"
+
+//go:generate moq -out mock/api.go -pkg mock -stub . API
+
+// API describes the Smartis operations exposed by Client.
+// Helpers accept API instead of *Client so that callers can substitute
+// fakes, caching or tracing wrappers.
+type API interface {
+	GetReport(ctx context.Context, payload Payload) ([]*Report, error)
+
+	GetProjects(ctx context.Context) ([]Project, error)
+	GetMetrics(ctx context.Context) ([]Metric, error)
+	GetGroupings(ctx context.Context) ([]Grouping, error)
+	GetAttributions(ctx context.Context) ([]AttributionSmartis, error)
+	GetChannels(ctx context.Context) ([]Channel, error)
+	GetPlacements(ctx context.Context) ([]Placement, error)
+	GetCampaigns(ctx context.Context, ids []int) ([]Campaign, error)
+	GetAds(ctx context.Context, ids []int) ([]Ad, error)
+	GetKeywords(ctx context.Context, ids []int) ([]Keyword, error)
+
+	GetCRMCustomFields(ctx context.Context, ids []int) ([]CrmCustomField, error)
+	GetCRMCustomFieldGroups(ctx context.Context, ids []int) ([]CrmCustomFieldGroup, error)
+}
+
+var _ API = (*Client)(nil)
+
+// Middleware wraps an API with additional behaviour.
+type Middleware func(next API) API
+
+// Chain wraps api with the given middlewares.
+// The first middleware is the outermost one, so it sees every call first.
+func Chain(api API, middlewares ...Middleware) API {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		api = middlewares[i](api)
+	}
+
+	return api
+}
+
+// Interceptor is called around every API method.
+// The method argument holds the method name, e.g. "GetReport".
+// Implementations must call next to perform the actual request.
+type Interceptor func(ctx context.Context, method string, next func(ctx context.Context) error) error
+
+// Intercept returns a Middleware that runs fn around every API call.
+func Intercept(fn Interceptor) Middleware {
+	return func(next API) API {
+		return &interceptedAPI{next: next, fn: fn}
+	}
+}
+
+type interceptedAPI struct {
+	next API
+	fn   Interceptor
+}
+
+func (a *interceptedAPI) GetReport(ctx context.Context, payload Payload) ([]*Report, error) {
+	var result []*Report
+
+	err := a.fn(ctx, "GetReport", func(ctx context.Context) (err error) {
+		result, err = a.next.GetReport(ctx, payload)
+
+		return err
+	})
+
+	return result, err
+}
+
+func (a *interceptedAPI) GetProjects(ctx context.Context) ([]Project, error) {
+	var result []Project
+
+	err := a.fn(ctx, "GetProjects", func(ctx context.Context) (err error) {
+		result, err = a.next.GetProjects(ctx)
+
+		return err
+	})
+
+	return result, err
+}
+
+func (a *interceptedAPI) GetMetrics(ctx context.Context) ([]Metric, error) {
+	var result []Metric
+
+	err := a.fn(ctx, "GetMetrics", func(ctx context.Context) (err error) {
+		result, err = a.next.GetMetrics(ctx)
+
+		return err
+	})
+
+	return result, err
+}
+
+func (a *interceptedAPI) GetGroupings(ctx context.Context) ([]Grouping, error) {
+	var result []Grouping
+
+	err := a.fn(ctx, "GetGroupings", func(ctx context.Context) (err error) {
+		result, err = a.next.GetGroupings(ctx)
+
+		return err
+	})
+
+	return result, err
+}
+
+func (a *interceptedAPI) GetAttributions(ctx context.Context) ([]AttributionSmartis, error) {
+	var result []AttributionSmartis
+
+	err := a.fn(ctx, "GetAttributions", func(ctx context.Context) (err error) {
+		result, err = a.next.GetAttributions(ctx)
+
+		return err
+	})
+
+	return result, err
+}
+
+func (a *interceptedAPI) GetChannels(ctx context.Context) ([]Channel, error) {
+	var result []Channel
+
+	err := a.fn(ctx, "GetChannels", func(ctx context.Context) (err error) {
+		result, err = a.next.GetChannels(ctx)
+
+		return err
+	})
+
+	return result, err
+}
+
+func (a *interceptedAPI) GetPlacements(ctx context.Context) ([]Placement, error) {
+	var result []Placement
+
+	err := a.fn(ctx, "GetPlacements", func(ctx context.Context) (err error) {
+		result, err = a.next.GetPlacements(ctx)
+
+		return err
+	})
+
+	return result, err
+}
+
+func (a *interceptedAPI) GetCampaigns(ctx context.Context, ids []int) ([]Campaign, error) {
+	var result []Campaign
+
+	err := a.fn(ctx, "GetCampaigns", func(ctx context.Context) (err error) {
+		result, err = a.next.GetCampaigns(ctx, ids)
+
+		return err
+	})
+
+	return result, err
+}
+
+func (a *interceptedAPI) GetAds(ctx context.Context, ids []int) ([]Ad, error) {
+	var result []Ad
+
+	err := a.fn(ctx, "GetAds", func(ctx context.Context) (err error) {
+		result, err = a.next.GetAds(ctx, ids)
+
+		return err
+	})
+
+	return result, err
+}
+
+func (a *interceptedAPI) GetKeywords(ctx context.Context, ids []int) ([]Keyword, error) {
+	var result []Keyword
+
+	err := a.fn(ctx, "GetKeywords", func(ctx context.Context) (err error) {
+		result, err = a.next.GetKeywords(ctx, ids)
+
+		return err
+	})
+
+	return result, err
+}
+
+func (a *interceptedAPI) GetCRMCustomFields(ctx context.Context, ids []int) ([]CrmCustomField, error) {
+	var result []CrmCustomField
+
+	err := a.fn(ctx, "GetCRMCustomFields", func(ctx context.Context) (err error) {
+		result, err = a.next.GetCRMCustomFields(ctx, ids)
+
+		return err
+	})
+
+	return result, err
+}
+
+func (a *interceptedAPI) GetCRMCustomFieldGroups(ctx context.Context, ids []int) ([]CrmCustomFieldGroup, error) {
+	var result []CrmCustomFieldGroup
+
+	err := a.fn(ctx, "GetCRMCustomFieldGroups", func(ctx context.Context) (err error) {
+		result, err = a.next.GetCRMCustomFieldGroups(ctx, ids)
+
+		return err
+	})
+
+	return result, err
+}
@@ -0,0 +1,215 @@
+package gosmartis
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+//go:embed attribution_models.json
+var bundledAttributionModelsJSON []byte
+
+var (
+	bundledAttributionModelsOnce sync.Once
+	bundledAttributionModels     []AttributionModelInfo
+)
+
+// BundledAttributionModels returns the system models known at build time.
+func BundledAttributionModels() []AttributionModelInfo {
+	bundledAttributionModelsOnce.Do(func() {
+		err := json.Unmarshal(bundledAttributionModelsJSON, &bundledAttributionModels)
+		if err != nil {
+			panic(fmt.Sprintf("gosmartis: invalid bundled attribution models: %v", err))
+		}
+	})
+
+	return append([]AttributionModelInfo(nil), bundledAttributionModels...)
+}
+
+// AttributionModelInfo describes an attribution model.
+type AttributionModelInfo struct {
+	ID      AttributionModel `json:"id"`
+	TitleEN string           `json:"title_en,omitempty"`
+	TitleRU string           `json:"title_ru,omitempty"`
+	About   string           `json:"about,omitempty"`
+	// IsSystem is false for models created in the account.
+	IsSystem bool `json:"is_system"`
+	// Live is true when the model was returned by GetAttributions.
+	Live bool `json:"live,omitempty"`
+}
+
+// Title returns the English title, falling back to the Russian one.
+func (i AttributionModelInfo) Title() string {
+	if i.TitleEN != "" {
+		return i.TitleEN
+	}
+
+	return i.TitleRU
+}
+
+// String returns the English title of a bundled model.
+func (m AttributionModel) String() string {
+	for _, info := range BundledAttributionModels() {
+		if info.ID == m {
+			return info.TitleEN
+		}
+	}
+
+	return fmt.Sprintf("AttributionModel(%d)", int(m))
+}
+
+// AttributionRegistry joins the bundled system models with the models the
+// API reports for the account, including custom ones. It is safe for
+// concurrent use.
+type AttributionRegistry struct {
+	mu     sync.RWMutex
+	models map[AttributionModel]AttributionModelInfo
+	live   bool
+}
+
+// NewAttributionRegistry returns a registry holding the bundled models, so
+// it works without calling the API.
+func NewAttributionRegistry() *AttributionRegistry {
+	r := &AttributionRegistry{models: make(map[AttributionModel]AttributionModelInfo)}
+
+	for _, info := range BundledAttributionModels() {
+		r.models[info.ID] = info
+	}
+
+	return r
+}
+
+// LoadAttributionRegistry returns a registry refreshed from the API.
+func LoadAttributionRegistry(ctx context.Context, api API) (*AttributionRegistry, error) {
+	r := NewAttributionRegistry()
+
+	err := r.Refresh(ctx, api)
+	if err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Refresh loads the account's models with GetAttributions and merges them.
+func (r *AttributionRegistry) Refresh(ctx context.Context, api API) error {
+	attributions, err := api.GetAttributions(ctx)
+	if err != nil {
+		return err
+	}
+
+	r.Merge(attributions)
+
+	return nil
+}
+
+// Merge adds the models returned by the API. Their titles are Russian;
+// bundled English titles are kept. Once merged, only live models validate.
+func (r *AttributionRegistry) Merge(attributions []AttributionSmartis) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, a := range attributions {
+		id := AttributionModel(a.ID)
+
+		info := r.models[id]
+		info.ID = id
+		info.TitleRU = a.Title
+		info.About = a.About
+		info.IsSystem = bool(a.IsSystem)
+		info.Live = true
+
+		r.models[id] = info
+	}
+
+	r.live = true
+}
+
+// Lookup returns the model with the given ID.
+func (r *AttributionRegistry) Lookup(id AttributionModel) (AttributionModelInfo, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	info, ok := r.valid(id)
+
+	return info, ok
+}
+
+func (r *AttributionRegistry) valid(id AttributionModel) (AttributionModelInfo, bool) {
+	info, ok := r.models[id]
+	if !ok || (r.live && !info.Live) {
+		return AttributionModelInfo{}, false
+	}
+
+	return info, true
+}
+
+// Model returns id as an AttributionModel if the registry knows it. Use it
+// to turn IDs of custom models into typed values.
+func (r *AttributionRegistry) Model(id int) (AttributionModel, error) {
+	_, ok := r.Lookup(AttributionModel(id))
+	if !ok {
+		return 0, fmt.Errorf("%w: %d", ErrUnknownAttributionModel, id)
+	}
+
+	return AttributionModel(id), nil
+}
+
+// ByTitle finds a model by its English or Russian title, ignoring case.
+func (r *AttributionRegistry) ByTitle(title string) (AttributionModel, bool) {
+	for _, info := range r.Models() {
+		if strings.EqualFold(info.TitleEN, title) || strings.EqualFold(info.TitleRU, title) {
+			return info.ID, true
+		}
+	}
+
+	return 0, false
+}
+
+// Models returns the valid models ordered by ID.
+func (r *AttributionRegistry) Models() []AttributionModelInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	result := make([]AttributionModelInfo, 0, len(r.models))
+
+	for id := range r.models {
+		if info, ok := r.valid(id); ok {
+			result = append(result, info)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+
+	return result
+}
+
+// Custom returns the account's own models.
+func (r *AttributionRegistry) Custom() []AttributionModelInfo {
+	var result []AttributionModelInfo
+
+	for _, info := range r.Models() {
+		if !info.IsSystem {
+			result = append(result, info)
+		}
+	}
+
+	return result
+}
+
+// Validate checks that a uses a known model and a sane period.
+func (r *AttributionRegistry) Validate(a Attribution) error {
+	if _, ok := r.Lookup(a.ModelID); !ok {
+		return fmt.Errorf("%w: %d", ErrUnknownAttributionModel, int(a.ModelID))
+	}
+
+	if a.Period < 0 {
+		return fmt.Errorf("attribution period is negative: %d", a.Period)
+	}
+
+	return nil
+}
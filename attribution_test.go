@@ -0,0 +1,140 @@
+package gosmartis_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/zfullio/gosmartis"
+	"github.com/zfullio/gosmartis/mock"
+)
+
+func TestBundledAttributionModels(t *testing.T) {
+	models := gosmartis.BundledAttributionModels()
+	if len(models) == 0 {
+		t.Fatal("no bundled models")
+	}
+
+	seen := make(map[gosmartis.AttributionModel]bool, len(models))
+
+	for _, info := range models {
+		if seen[info.ID] {
+			t.Errorf("model %d is bundled twice", info.ID)
+		}
+
+		seen[info.ID] = true
+
+		if info.TitleEN == "" || info.TitleRU == "" || !info.IsSystem {
+			t.Errorf("bundled model %+v lacks titles or is not a system model", info)
+		}
+	}
+
+	models[0].TitleEN = "changed"
+	if gosmartis.BundledAttributionModels()[0].TitleEN == "changed" {
+		t.Error("callers can modify the bundled models")
+	}
+
+	if got := gosmartis.AttributionModelLastClick.String(); got != "Last click" {
+		t.Errorf("String = %q", got)
+	}
+
+	if got := gosmartis.AttributionModel(999).String(); got != "AttributionModel(999)" {
+		t.Errorf("unknown String = %q", got)
+	}
+}
+
+func TestAttributionRegistryBundled(t *testing.T) {
+	r := gosmartis.NewAttributionRegistry()
+
+	err := r.Validate(gosmartis.Attribution{ModelID: gosmartis.AttributionModelFirstClick, Period: 30})
+	if err != nil {
+		t.Errorf("bundled model: %v", err)
+	}
+
+	err = r.Validate(gosmartis.Attribution{ModelID: 999})
+	if !errors.Is(err, gosmartis.ErrUnknownAttributionModel) {
+		t.Errorf("unknown model: %v", err)
+	}
+
+	err = r.Validate(gosmartis.Attribution{ModelID: gosmartis.AttributionModelLastClick, Period: -1})
+	if err == nil {
+		t.Error("negative period is valid")
+	}
+
+	if id, ok := r.ByTitle("first CLICK"); !ok || id != gosmartis.AttributionModelFirstClick {
+		t.Errorf("ByTitle en = %v, %v", id, ok)
+	}
+
+	if id, ok := r.ByTitle("Первое касание"); !ok || id != gosmartis.AttributionModelFirstClick {
+		t.Errorf("ByTitle ru = %v, %v", id, ok)
+	}
+
+	if _, ok := r.ByTitle("nope"); ok {
+		t.Error("ByTitle found an unknown title")
+	}
+
+	if len(r.Custom()) != 0 {
+		t.Errorf("custom = %+v, want none", r.Custom())
+	}
+}
+
+func TestAttributionRegistryLive(t *testing.T) {
+	api := &mock.APIMock{
+		GetAttributionsFunc: func(context.Context) ([]gosmartis.AttributionSmartis, error) {
+			return []gosmartis.AttributionSmartis{
+				{ID: 1, Title: "Последний клик", About: "system", IsSystem: true},
+				{ID: 101, Title: "Своя модель", IsSystem: false},
+			}, nil
+		},
+	}
+
+	r, err := gosmartis.LoadAttributionRegistry(context.Background(), api)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	models := r.Models()
+	if len(models) != 2 || models[0].ID != 1 || models[1].ID != 101 {
+		t.Fatalf("models = %+v, want only the live models 1 and 101", models)
+	}
+
+	if models[0].TitleEN != "Last click" || models[0].TitleRU != "Последний клик" || !models[0].Live {
+		t.Errorf("merged system model = %+v", models[0])
+	}
+
+	// Bundled models the account does not return are no longer valid.
+	err = r.Validate(gosmartis.Attribution{ModelID: gosmartis.AttributionModelFirstClick})
+	if !errors.Is(err, gosmartis.ErrUnknownAttributionModel) {
+		t.Errorf("model missing from the account: %v", err)
+	}
+
+	id, err := r.Model(101)
+	if err != nil || id != 101 {
+		t.Errorf("Model(101) = %v, %v", id, err)
+	}
+
+	if _, err = r.Model(2); !errors.Is(err, gosmartis.ErrUnknownAttributionModel) {
+		t.Errorf("Model(2) = %v", err)
+	}
+
+	if id, ok := r.ByTitle("своя модель"); !ok || id != 101 {
+		t.Errorf("ByTitle custom = %v, %v", id, ok)
+	}
+
+	custom := r.Custom()
+	if len(custom) != 1 || custom[0].ID != 101 || custom[0].Title() != "Своя модель" {
+		t.Errorf("custom = %+v", custom)
+	}
+}
+
+func TestLoadAttributionRegistryError(t *testing.T) {
+	want := errors.New("down")
+	api := &mock.APIMock{
+		GetAttributionsFunc: func(context.Context) ([]gosmartis.AttributionSmartis, error) { return nil, want },
+	}
+
+	_, err := gosmartis.LoadAttributionRegistry(context.Background(), api)
+	if !errors.Is(err, want) {
+		t.Errorf("err = %v", err)
+	}
+}
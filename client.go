@@ -8,6 +8,8 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"sync"
+	"time"
 )
 
 const (
@@ -19,6 +21,24 @@ type Client struct {
 	CRMToken   string
 	HOST       string
 	HTTPClient *http.Client
+	// Limiter throttles requests when set. It is shared by concurrent calls
+	// such as CompareAttributions.
+	Limiter RateLimiter
+	// Attributions validates the attribution model of GetReport payloads
+	// when set.
+	Attributions *AttributionRegistry
+	// Credentials supplies the API key and CRM token when set, taking
+	// precedence over APIKey and CRMToken. Credentials in the request
+	// context, see WithCredentials, take precedence over both.
+	Credentials CredentialProvider
+	// Observer is notified of calls and requests when set, see Observer.
+	Observer Observer
+
+	projectsMu      sync.Mutex
+	projects        map[string]Project
+	projectsAt      time.Time
+	projectsMissing map[string]time.Time
+	projectsLoad    *projectsLoad
 }
 
 func NewClient(apiKey, crmToken string, httpClient *http.Client) *Client {
@@ -82,11 +102,29 @@ func statusCodeHandler(resp *http.Response) error {
 }
 
 func (c *Client) doRequest(ctx context.Context, method Method, data interface{}) (*http.Response, error) {
+	creds, err := c.credentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+
 	header := map[string]string{
-		"Authorization": fmt.Sprintf("Bearer %s", c.APIKey),
+		"Authorization": fmt.Sprintf("Bearer %s", creds.APIKey.Reveal()),
 		"Content-Type":  "application/json",
 	}
 
+	var wait time.Duration
+
+	if c.Limiter != nil {
+		start := time.Now()
+
+		err := c.Limiter.Wait(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		wait = time.Since(start)
+	}
+
 	var buf bytes.Buffer
 	enc := json.NewEncoder(&buf)
 
@@ -97,8 +135,19 @@ func (c *Client) doRequest(ctx context.Context, method Method, data interface{})
 		}
 	}
 
+	end := func(RequestResult) {}
+	if c.Observer != nil {
+		ctx, end = c.Observer.StartRequest(ctx, Request{
+			Endpoint: method.endpoint,
+			Bytes:    buf.Len(),
+			Wait:     wait,
+		})
+	}
+
 	req, err := http.NewRequest(http.MethodPost, method.getURL(), &buf)
 	if err != nil {
+		end(RequestResult{Err: err})
+
 		return nil, err
 	}
 
@@ -110,18 +159,42 @@ func (c *Client) doRequest(ctx context.Context, method Method, data interface{})
 
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
+		err = redactError(err, creds)
+		end(RequestResult{Err: err})
+
 		return nil, err
 	}
 
 	err = statusCodeHandler(resp)
 	if err != nil {
+		err = redactError(err, creds)
+		end(RequestResult{StatusCode: resp.StatusCode, Err: err})
+
 		return nil, err
 	}
 
+	if c.Observer != nil {
+		resp.Body = &observedBody{ReadCloser: resp.Body, result: RequestResult{StatusCode: resp.StatusCode}, end: end}
+	}
+
 	return resp, nil
 }
 
-func (c *Client) GetReport(ctx context.Context, payload Payload) ([]*Report, error) {
+func (c *Client) GetReport(ctx context.Context, payload Payload) (items []*Report, err error) {
+	ctx, end := c.startCall(ctx, Call{Method: "GetReport", Payload: payload})
+	defer func() {
+		if c.Observer != nil {
+			end(countRows(items), err)
+		}
+	}()
+
+	if c.Attributions != nil {
+		err := c.Attributions.Validate(payload.Attribution)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	pay := payload.convert()
 
 	resp, err := c.doRequest(ctx, GetReports, pay)
@@ -144,7 +217,7 @@ func (c *Client) GetReport(ctx context.Context, payload Payload) ([]*Report, err
 	}
 
 	if len(data.Reports) == 0 {
-		return nil, fmt.Errorf("no reports data")
+		return nil, ErrNoReportData
 	}
 
 	reports := make([]*Report, 0, len(data.Reports))
@@ -198,7 +271,10 @@ func newReport(metric string, data interface{}) (*Report, error) {
 
 // GetProjects retrieves a list of projects using the provided context.
 // It returns a slice of Project and an error.
-func (c *Client) GetProjects(ctx context.Context) ([]Project, error) {
+func (c *Client) GetProjects(ctx context.Context) (items []Project, err error) {
+	ctx, end := c.startCall(ctx, Call{Method: "GetProjects"})
+	defer func() { end(len(items), err) }()
+
 	resp, err := c.doRequest(ctx, GetProjects, nil)
 	if err != nil {
 		return nil, err
@@ -218,7 +294,10 @@ func (c *Client) GetProjects(ctx context.Context) ([]Project, error) {
 
 // GetMetrics retrieves a list of metrics using the provided context.
 // It returns a slice of Metric and an error.
-func (c *Client) GetMetrics(ctx context.Context) ([]Metric, error) {
+func (c *Client) GetMetrics(ctx context.Context) (items []Metric, err error) {
+	ctx, end := c.startCall(ctx, Call{Method: "GetMetrics"})
+	defer func() { end(len(items), err) }()
+
 	resp, err := c.doRequest(ctx, GetMetrics, nil)
 	if err != nil {
 		return nil, err
@@ -238,7 +317,10 @@ func (c *Client) GetMetrics(ctx context.Context) ([]Metric, error) {
 
 // GetGroupings retrieves a list of groupings using the provided context.
 // It returns a slice of Grouping and an error.
-func (c *Client) GetGroupings(ctx context.Context) ([]Grouping, error) {
+func (c *Client) GetGroupings(ctx context.Context) (items []Grouping, err error) {
+	ctx, end := c.startCall(ctx, Call{Method: "GetGroupings"})
+	defer func() { end(len(items), err) }()
+
 	resp, err := c.doRequest(ctx, GetGroupings, nil)
 	if err != nil {
 		return nil, err
@@ -258,7 +340,10 @@ func (c *Client) GetGroupings(ctx context.Context) ([]Grouping, error) {
 
 // GetAttributions retrieves a list of attributions using the provided context.
 // It returns a slice of AttributionSmartis and an error.
-func (c *Client) GetAttributions(ctx context.Context) ([]AttributionSmartis, error) {
+func (c *Client) GetAttributions(ctx context.Context) (items []AttributionSmartis, err error) {
+	ctx, end := c.startCall(ctx, Call{Method: "GetAttributions"})
+	defer func() { end(len(items), err) }()
+
 	resp, err := c.doRequest(ctx, GetAttributions, nil)
 	if err != nil {
 		return nil, err
@@ -278,7 +363,10 @@ func (c *Client) GetAttributions(ctx context.Context) ([]AttributionSmartis, err
 
 // GetChannels retrieves a list of channels using the provided context.
 // It returns a slice of Channel and an error.
-func (c *Client) GetChannels(ctx context.Context) ([]Channel, error) {
+func (c *Client) GetChannels(ctx context.Context) (items []Channel, err error) {
+	ctx, end := c.startCall(ctx, Call{Method: "GetChannels"})
+	defer func() { end(len(items), err) }()
+
 	resp, err := c.doRequest(ctx, GetChannels, nil)
 	if err != nil {
 		return nil, err
@@ -298,7 +386,10 @@ func (c *Client) GetChannels(ctx context.Context) ([]Channel, error) {
 
 // GetPlacements retrieves a list of placements using the provided context.
 // It returns a slice of Placement and an error.
-func (c *Client) GetPlacements(ctx context.Context) ([]Placement, error) {
+func (c *Client) GetPlacements(ctx context.Context) (items []Placement, err error) {
+	ctx, end := c.startCall(ctx, Call{Method: "GetPlacements"})
+	defer func() { end(len(items), err) }()
+
 	resp, err := c.doRequest(ctx, GetPlacements, nil)
 	if err != nil {
 		return nil, err
@@ -318,7 +409,10 @@ func (c *Client) GetPlacements(ctx context.Context) ([]Placement, error) {
 
 // GetCampaigns retrieves a list of campaigns using the provided context.
 // It returns a slice of Campaign and an error.
-func (c *Client) GetCampaigns(ctx context.Context, ids []int) ([]Campaign, error) {
+func (c *Client) GetCampaigns(ctx context.Context, ids []int) (items []Campaign, err error) {
+	ctx, end := c.startCall(ctx, Call{Method: "GetCampaigns", IDs: len(ids)})
+	defer func() { end(len(items), err) }()
+
 	data := map[string]interface{}{
 		"ids": ids,
 	}
@@ -342,7 +436,10 @@ func (c *Client) GetCampaigns(ctx context.Context, ids []int) ([]Campaign, error
 
 // GetAds retrieves a list of ads using the provided context.
 // It returns a slice of Ad and an error.
-func (c *Client) GetAds(ctx context.Context, ids []int) ([]Ad, error) {
+func (c *Client) GetAds(ctx context.Context, ids []int) (items []Ad, err error) {
+	ctx, end := c.startCall(ctx, Call{Method: "GetAds", IDs: len(ids)})
+	defer func() { end(len(items), err) }()
+
 	data := map[string]interface{}{
 		"ids": ids,
 	}
@@ -366,7 +463,10 @@ func (c *Client) GetAds(ctx context.Context, ids []int) ([]Ad, error) {
 
 // GetKeywords retrieves a list of keywords using the provided context.
 // It returns a slice of Keyword and an error.
-func (c *Client) GetKeywords(ctx context.Context, ids []int) ([]Keyword, error) {
+func (c *Client) GetKeywords(ctx context.Context, ids []int) (items []Keyword, err error) {
+	ctx, end := c.startCall(ctx, Call{Method: "GetKeywords", IDs: len(ids)})
+	defer func() { end(len(items), err) }()
+
 	data := map[string]interface{}{
 		"ids": ids,
 	}
@@ -390,13 +490,25 @@ func (c *Client) GetKeywords(ctx context.Context, ids []int) ([]Keyword, error)
 
 // GetCRMCustomFields retrieves a list of custom fields using the provided context.
 // It returns a slice of CrmCustomField and an error.
-func (c *Client) GetCRMCustomFields(ctx context.Context, ids []int) ([]CrmCustomField, error) {
+func (c *Client) GetCRMCustomFields(ctx context.Context, ids []int) (items []CrmCustomField, err error) {
+	ctx, end := c.startCall(ctx, Call{Method: "GetCRMCustomFields", IDs: len(ids)})
+	defer func() { end(len(items), err) }()
+
+	creds, err := c.credentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if creds.CRMToken == "" {
+		return nil, ErrEmptyCRMToken
+	}
+
 	data := map[string]interface{}{
 		"ids":               ids,
-		"smartis_crm_token": c.CRMToken,
+		"smartis_crm_token": creds.CRMToken.Reveal(),
 	}
 
-	resp, err := c.doRequest(ctx, GetCRMCustomFields, data)
+	resp, err := c.doRequest(WithCredentials(ctx, creds), GetCRMCustomFields, data)
 	if err != nil {
 		return nil, err
 	}
@@ -415,13 +527,25 @@ func (c *Client) GetCRMCustomFields(ctx context.Context, ids []int) ([]CrmCustom
 
 // GetCRMCustomFieldGroups retrieves a list of custom field groups using the provided context.
 // It returns a slice of CrmCustomFieldGroup and an error.
-func (c *Client) GetCRMCustomFieldGroups(ctx context.Context, ids []int) ([]CrmCustomFieldGroup, error) {
+func (c *Client) GetCRMCustomFieldGroups(ctx context.Context, ids []int) (items []CrmCustomFieldGroup, err error) {
+	ctx, end := c.startCall(ctx, Call{Method: "GetCRMCustomFieldGroups", IDs: len(ids)})
+	defer func() { end(len(items), err) }()
+
+	creds, err := c.credentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if creds.CRMToken == "" {
+		return nil, ErrEmptyCRMToken
+	}
+
 	data := map[string]interface{}{
 		"ids":               ids,
-		"smartis_crm_token": c.CRMToken,
+		"smartis_crm_token": creds.CRMToken.Reveal(),
 	}
 
-	resp, err := c.doRequest(ctx, GetCRMCustomFieldGroups, data)
+	resp, err := c.doRequest(WithCredentials(ctx, creds), GetCRMCustomFieldGroups, data)
 	if err != nil {
 		return nil, err
 	}
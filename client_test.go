@@ -0,0 +1,73 @@
+package gosmartis
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// fakeAPI answers requests by endpoint with handle, which gets the decoded
+// request body.
+type fakeAPI struct {
+	mu       sync.Mutex
+	requests []fakeRequest
+	handle   func(endpoint string, body map[string]interface{}) (int, string)
+}
+
+type fakeRequest struct {
+	endpoint string
+	header   http.Header
+	body     map[string]interface{}
+}
+
+func (f *fakeAPI) client() *Client {
+	return NewClient("key", "token", &http.Client{Transport: roundTripFunc(f.roundTrip)})
+}
+
+func (f *fakeAPI) roundTrip(req *http.Request) (*http.Response, error) {
+	var body map[string]interface{}
+
+	err := json.NewDecoder(req.Body).Decode(&body)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	endpoint := strings.TrimPrefix(req.URL.String(), baseURL)
+
+	f.mu.Lock()
+	f.requests = append(f.requests, fakeRequest{endpoint: endpoint, header: req.Header, body: body})
+	f.mu.Unlock()
+
+	status, resp := f.handle(endpoint, body)
+
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(resp)),
+		Request:    req,
+	}, nil
+}
+
+func (f *fakeAPI) count(endpoint string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	n := 0
+
+	for _, req := range f.requests {
+		if req.endpoint == endpoint {
+			n++
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,401 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zfullio/gosmartis"
+)
+
+const dateLayout = "2006-01-02"
+
+func runProjects(ctx context.Context, e *env, args []string) error {
+	err := parseNoFlags("projects", args)
+	if err != nil {
+		return err
+	}
+
+	items, err := e.client.GetProjects(ctx)
+	if err != nil {
+		return err
+	}
+
+	return e.writeItems(items)
+}
+
+func runMetrics(ctx context.Context, e *env, args []string) error {
+	err := parseNoFlags("metrics", args)
+	if err != nil {
+		return err
+	}
+
+	items, err := e.client.GetMetrics(ctx)
+	if err != nil {
+		return err
+	}
+
+	return e.writeItems(items)
+}
+
+func runGroupings(ctx context.Context, e *env, args []string) error {
+	err := parseNoFlags("groupings", args)
+	if err != nil {
+		return err
+	}
+
+	items, err := e.client.GetGroupings(ctx)
+	if err != nil {
+		return err
+	}
+
+	return e.writeItems(items)
+}
+
+func runAttributions(ctx context.Context, e *env, args []string) error {
+	err := parseNoFlags("attributions", args)
+	if err != nil {
+		return err
+	}
+
+	items, err := e.client.GetAttributions(ctx)
+	if err != nil {
+		return err
+	}
+
+	return e.writeItems(items)
+}
+
+func runChannels(ctx context.Context, e *env, args []string) error {
+	err := parseNoFlags("channels", args)
+	if err != nil {
+		return err
+	}
+
+	items, err := e.client.GetChannels(ctx)
+	if err != nil {
+		return err
+	}
+
+	return e.writeItems(items)
+}
+
+func runPlacements(ctx context.Context, e *env, args []string) error {
+	err := parseNoFlags("placements", args)
+	if err != nil {
+		return err
+	}
+
+	items, err := e.client.GetPlacements(ctx)
+	if err != nil {
+		return err
+	}
+
+	return e.writeItems(items)
+}
+
+func runCampaigns(ctx context.Context, e *env, args []string) error {
+	ids, err := parseIDs("campaigns", args)
+	if err != nil {
+		return err
+	}
+
+	items, err := e.client.GetCampaigns(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	return e.writeItems(items)
+}
+
+func runAds(ctx context.Context, e *env, args []string) error {
+	ids, err := parseIDs("ads", args)
+	if err != nil {
+		return err
+	}
+
+	items, err := e.client.GetAds(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	return e.writeItems(items)
+}
+
+func runKeywords(ctx context.Context, e *env, args []string) error {
+	ids, err := parseIDs("keywords", args)
+	if err != nil {
+		return err
+	}
+
+	items, err := e.client.GetKeywords(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	return e.writeItems(items)
+}
+
+func runCRMFields(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("crm-fields", flag.ContinueOnError)
+	groups := fs.Bool("groups", false, "list custom field groups instead of fields")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: smartis crm-fields [-groups] [id ...]")
+		fs.PrintDefaults()
+	}
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	ids, err := atoiAll(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	if *groups {
+		items, err := e.client.GetCRMCustomFieldGroups(ctx, ids)
+		if err != nil {
+			return err
+		}
+
+		return e.writeItems(items)
+	}
+
+	items, err := e.client.GetCRMCustomFields(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	return e.writeItems(items)
+}
+
+func runCRMSchema(ctx context.Context, e *env, args []string) error {
+	fs := flag.NewFlagSet("crm-schema", flag.ContinueOnError)
+	markdown := fs.Bool("markdown", false, "write Markdown instead of JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: smartis crm-schema [-markdown]")
+		fs.PrintDefaults()
+	}
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	schema, err := gosmartis.LoadCRMSchema(ctx, e.client)
+	if err != nil {
+		return err
+	}
+
+	if *markdown {
+		return schema.WriteMarkdown(e.out)
+	}
+
+	return schema.WriteJSON(e.out)
+}
+
+func runReport(ctx context.Context, e *env, args []string) error {
+	var filters filterFlag
+
+	yesterday := time.Now().AddDate(0, 0, -1).Format(dateLayout)
+
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	project := fs.String("project", "", "project code (required)")
+	metrics := fs.String("metrics", "", "comma-separated metric codes (required)")
+	from := fs.String("from", yesterday, "first day, YYYY-MM-DD")
+	to := fs.String("to", yesterday, "last day, YYYY-MM-DD")
+	groupBy := fs.String("group-by", string(gosmartis.GroupByDay), "grouping code")
+	typeReport := fs.String("type", string(gosmartis.TypeReportAggregated), "report type: aggregated or raw")
+	fields := fs.String("fields", "", "comma-separated fields for raw reports")
+	model := fs.Int("model", int(gosmartis.AttributionModelLastClick), "attribution model id")
+	period := fs.Int("period", 0, "attribution period in days")
+	withDirect := fs.Bool("with-direct", false, "count direct visits in attribution")
+	names := fs.Bool("names", false, "resolve CRM custom field column names")
+	fs.Var(&filters, "filter", "filter as name:operator:value, may be repeated")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: smartis report -project CODE -metrics CODE[,CODE] [flags]")
+		fs.PrintDefaults()
+	}
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if *project == "" || *metrics == "" {
+		fs.Usage()
+
+		return errors.New("report: -project and -metrics are required")
+	}
+
+	dateFrom, err := time.Parse(dateLayout, *from)
+	if err != nil {
+		return fmt.Errorf("report: -from: %w", err)
+	}
+
+	dateTo, err := time.Parse(dateLayout, *to)
+	if err != nil {
+		return fmt.Errorf("report: -to: %w", err)
+	}
+
+	payload := gosmartis.Payload{
+		Project:      *project,
+		Metrics:      splitList(*metrics),
+		DateTimeFrom: dateFrom,
+		DateTimeTo:   dateTo,
+		GroupBy:      gosmartis.GroupBy(*groupBy),
+		TypeReport:   gosmartis.TypeReport(*typeReport),
+		Filters:      filters,
+		Fields:       splitList(*fields),
+		Attribution: gosmartis.Attribution{
+			ModelID:    gosmartis.AttributionModel(*model),
+			Period:     *period,
+			WithDirect: *withDirect,
+		},
+	}
+
+	reports, err := e.client.GetReport(ctx, payload)
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(reports, func(i, j int) bool { return reports[i].Metric < reports[j].Metric })
+
+	if *names {
+		for _, report := range reports {
+			err = report.GetColumnsNames(ctx, e.client)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	columns, records := reportRecords(reports, *names)
+
+	return e.writeRecords(columns, records)
+}
+
+// reportRecords flattens reports into records with a leading "metric" column
+// followed by the union of all report columns in sorted order.
+func reportRecords(reports []*gosmartis.Report, useNames bool) ([]string, []map[string]interface{}) {
+	seen := make(map[string]bool)
+	records := make([]map[string]interface{}, 0)
+
+	for _, report := range reports {
+		for _, row := range report.RowsMassive {
+			record := make(map[string]interface{}, len(row)+1)
+			record["metric"] = report.Metric
+
+			for _, cell := range row {
+				name := cell.ColumnID
+				if useNames && cell.Name != "" {
+					name = cell.Name
+				}
+
+				record[name] = cell.Value
+				seen[name] = true
+			}
+
+			records = append(records, record)
+		}
+	}
+
+	columns := make([]string, 0, len(seen))
+	for name := range seen {
+		if name != "metric" {
+			columns = append(columns, name)
+		}
+	}
+
+	sort.Strings(columns)
+
+	return append([]string{"metric"}, columns...), records
+}
+
+type filterFlag []gosmartis.Filter
+
+func (f *filterFlag) String() string {
+	parts := make([]string, 0, len(*f))
+	for _, filter := range *f {
+		parts = append(parts, filter.Name+":"+filter.Operator+":"+filter.Value)
+	}
+
+	return strings.Join(parts, ",")
+}
+
+func (f *filterFlag) Set(value string) error {
+	parts := strings.SplitN(value, ":", 3)
+	if len(parts) != 3 {
+		return fmt.Errorf("invalid filter %q, want name:operator:value", value)
+	}
+
+	*f = append(*f, gosmartis.Filter{Name: parts[0], Operator: parts[1], Value: parts[2]})
+
+	return nil
+}
+
+func parseNoFlags(name string, args []string) error {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprintf(fs.Output(), "Usage: smartis %s\n", name) }
+
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != 0 {
+		return fmt.Errorf("%s: unexpected arguments %v", name, fs.Args())
+	}
+
+	return nil
+}
+
+func parseIDs(name string, args []string) ([]int, error) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() { fmt.Fprintf(fs.Output(), "Usage: smartis %s [id ...]\n", name) }
+
+	err := fs.Parse(args)
+	if err != nil {
+		return nil, err
+	}
+
+	return atoiAll(fs.Args())
+}
+
+// atoiAll parses ids given as separate arguments or comma-separated lists.
+func atoiAll(args []string) ([]int, error) {
+	ids := make([]int, 0, len(args))
+
+	for _, arg := range args {
+		for _, part := range splitList(arg) {
+			id, err := strconv.Atoi(part)
+			if err != nil {
+				return nil, fmt.Errorf("invalid id %q", part)
+			}
+
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
+func splitList(value string) []string {
+	var result []string
+
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part != "" {
+			result = append(result, part)
+		}
+	}
+
+	return result
+}
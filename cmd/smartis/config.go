@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const (
+	envAPIKey   = "SMARTIS_API_KEY"
+	envCRMToken = "SMARTIS_CRM_TOKEN"
+	envConfig   = "SMARTIS_CONFIG"
+)
+
+type config struct {
+	APIKey   string `json:"api_key"`
+	CRMToken string `json:"crm_token"`
+}
+
+// loadConfig reads the config file, if any, and lets environment variables
+// override its values. An explicitly given path must exist.
+func loadConfig(path string) (config, error) {
+	var cfg config
+
+	explicit := path != ""
+	if !explicit {
+		path = os.Getenv(envConfig)
+		explicit = path != ""
+	}
+
+	if !explicit {
+		dir, err := os.UserConfigDir()
+		if err == nil {
+			path = filepath.Join(dir, "smartis", "config.json")
+		}
+	}
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+
+		switch {
+		case err == nil:
+			err = json.Unmarshal(data, &cfg)
+			if err != nil {
+				return cfg, fmt.Errorf("parse config %s: %w", path, err)
+			}
+		case errors.Is(err, fs.ErrNotExist) && !explicit:
+		default:
+			return cfg, fmt.Errorf("read config: %w", err)
+		}
+	}
+
+	if v := os.Getenv(envAPIKey); v != "" {
+		cfg.APIKey = v
+	}
+
+	if v := os.Getenv(envCRMToken); v != "" {
+		cfg.CRMToken = v
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zfullio/gosmartis/recorder"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+
+	err := os.WriteFile(path, []byte(content), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadConfigPrecedence(t *testing.T) {
+	fileConfig := writeConfig(t, "file.json", `{"api_key": "file-key", "crm_token": "file-token"}`)
+	envFile := writeConfig(t, "env.json", `{"api_key": "env-file-key"}`)
+	userDir := t.TempDir()
+
+	err := os.MkdirAll(filepath.Join(userDir, "smartis"), 0o700)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = os.WriteFile(filepath.Join(userDir, "smartis", "config.json"), []byte(`{"api_key": "default-key"}`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		flag string
+		env  map[string]string
+		want config
+	}{
+		{
+			name: "default file",
+			want: config{APIKey: "default-key"},
+		},
+		{
+			name: "config env over default file",
+			env:  map[string]string{envConfig: envFile},
+			want: config{APIKey: "env-file-key"},
+		},
+		{
+			name: "config flag over config env",
+			flag: fileConfig,
+			env:  map[string]string{envConfig: envFile},
+			want: config{APIKey: "file-key", CRMToken: "file-token"},
+		},
+		{
+			name: "key env over file",
+			flag: fileConfig,
+			env:  map[string]string{envAPIKey: "env-key"},
+			want: config{APIKey: "env-key", CRMToken: "file-token"},
+		},
+		{
+			name: "token env over file",
+			flag: fileConfig,
+			env:  map[string]string{envCRMToken: "env-token"},
+			want: config{APIKey: "file-key", CRMToken: "env-token"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("XDG_CONFIG_HOME", userDir)
+			t.Setenv(envConfig, "")
+			t.Setenv(envAPIKey, "")
+			t.Setenv(envCRMToken, "")
+
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			got, err := loadConfig(tt.flag)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want {
+				t.Errorf("loadConfig(%q) = %+v, want %+v", tt.flag, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfigMissing(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv(envConfig, "")
+	t.Setenv(envAPIKey, "env-key")
+
+	cfg, err := loadConfig("")
+	if err != nil || cfg.APIKey != "env-key" {
+		t.Errorf("missing default file = %+v, %v", cfg, err)
+	}
+
+	_, err = loadConfig(filepath.Join(t.TempDir(), "missing.json"))
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("missing explicit file: %v", err)
+	}
+
+	_, err = loadConfig(writeConfig(t, "bad.json", `{`))
+	if err == nil {
+		t.Error("invalid config is accepted")
+	}
+}
+
+func TestRunUsesConfiguredKey(t *testing.T) {
+	rec, err := recorder.New(filepath.Join("testdata", "report.json"), recorder.ModeReplay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var authorization string
+
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		authorization = req.Header.Get("Authorization")
+
+		return rec.RoundTrip(req)
+	})}
+
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv(envConfig, writeConfig(t, "env.json", `{"api_key": "env-file-key"}`))
+	t.Setenv(envAPIKey, "")
+
+	args := append([]string{"-config", writeConfig(t, "flag.json", `{"api_key": "flag-file-key"}`)}, reportArgs...)
+
+	err = run(args, &bytes.Buffer{}, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if authorization != "Bearer flag-file-key" {
+		t.Errorf("Authorization = %q", authorization)
+	}
+
+	t.Setenv(envAPIKey, "env-key")
+
+	err = run(args, &bytes.Buffer{}, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if authorization != "Bearer env-key" {
+		t.Errorf("Authorization with %s = %q", envAPIKey, authorization)
+	}
+}
+
+func TestRunWithoutKey(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv(envConfig, "")
+	t.Setenv(envAPIKey, "")
+
+	err := run([]string{"projects"}, &bytes.Buffer{}, &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		t.Error("request sent without an api key")
+
+		return nil, errors.New("unexpected request")
+	})})
+	if err == nil {
+		t.Error("run without an api key succeeded")
+	}
+}
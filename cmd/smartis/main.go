@@ -0,0 +1,148 @@
+// Command smartis runs ad-hoc queries against the Smartis API.
+//
+// Usage:
+//
+//	smartis [global flags] <command> [command flags]
+//
+// Credentials are read from the SMARTIS_API_KEY and SMARTIS_CRM_TOKEN
+// environment variables or from a JSON config file, see -config.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/zfullio/gosmartis"
+)
+
+type command struct {
+	name    string
+	summary string
+	run     func(ctx context.Context, env *env, args []string) error
+}
+
+var commands = []command{
+	{name: "projects", summary: "list projects", run: runProjects},
+	{name: "metrics", summary: "list metrics", run: runMetrics},
+	{name: "groupings", summary: "list groupings", run: runGroupings},
+	{name: "attributions", summary: "list attribution models", run: runAttributions},
+	{name: "channels", summary: "list channels", run: runChannels},
+	{name: "placements", summary: "list placements", run: runPlacements},
+	{name: "campaigns", summary: "list campaigns by id", run: runCampaigns},
+	{name: "ads", summary: "list ads by id", run: runAds},
+	{name: "keywords", summary: "list keywords by id", run: runKeywords},
+	{name: "crm-fields", summary: "list CRM custom fields or groups by id", run: runCRMFields},
+	{name: "crm-schema", summary: "show CRM custom fields nested under their groups", run: runCRMSchema},
+	{name: "report", summary: "run a report", run: runReport},
+}
+
+// env carries what every command needs.
+type env struct {
+	client *gosmartis.Client
+	out    io.Writer
+	format format
+}
+
+func main() {
+	err := run(os.Args[1:], os.Stdout, &http.Client{})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "smartis:", err)
+		os.Exit(1)
+	}
+}
+
+// run executes the command line args, sending requests with httpClient.
+func run(args []string, out io.Writer, httpClient *http.Client) error {
+	fs := flag.NewFlagSet("smartis", flag.ContinueOnError)
+	configPath := fs.String("config", "", "path to JSON config file (default $SMARTIS_CONFIG or ~/.config/smartis/config.json)")
+	formatName := fs.String("format", string(formatTable), "output format: table, json, ndjson or csv")
+	timeout := fs.Duration("timeout", 2*time.Minute, "request timeout")
+	fs.Usage = func() { usage(fs) }
+
+	err := fs.Parse(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		usage(fs)
+
+		return errors.New("no command given")
+	}
+
+	cmd, ok := findCommand(fs.Arg(0))
+	if !ok {
+		return fmt.Errorf("unknown command %q", fs.Arg(0))
+	}
+
+	outFormat, err := parseFormat(*formatName)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		return err
+	}
+
+	if cfg.APIKey == "" {
+		return errors.New("api key is not set, use SMARTIS_API_KEY or the config file")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	e := &env{
+		client: gosmartis.NewClient(cfg.APIKey, cfg.CRMToken, httpClient),
+		out:    out,
+		format: outFormat,
+	}
+
+	err = cmd.run(ctx, e, fs.Args()[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+
+	return err
+}
+
+func findCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+
+	return command{}, false
+}
+
+func usage(fs *flag.FlagSet) {
+	w := fs.Output()
+
+	fmt.Fprintln(w, "Usage: smartis [global flags] <command> [command flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+
+	names := make([]string, 0, len(commands))
+	for _, cmd := range commands {
+		names = append(names, fmt.Sprintf("  %-13s %s", cmd.name, cmd.summary))
+	}
+
+	sort.Strings(names)
+	fmt.Fprintln(w, strings.Join(names, "\n"))
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Global flags:")
+	fs.PrintDefaults()
+}
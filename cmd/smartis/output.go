@@ -0,0 +1,219 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/zfullio/gosmartis"
+)
+
+type format string
+
+const (
+	formatTable  format = "table"
+	formatJSON   format = "json"
+	formatNDJSON format = "ndjson"
+	formatCSV    format = "csv"
+)
+
+func parseFormat(name string) (format, error) {
+	switch f := format(strings.ToLower(name)); f {
+	case formatTable, formatJSON, formatNDJSON, formatCSV:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown format %q", name)
+	}
+}
+
+// writeItems prints a slice of API entities. JSON formats keep the entity
+// structure; table and CSV flatten it to one column per JSON field.
+func (e *env) writeItems(items interface{}) error {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice {
+		return fmt.Errorf("cannot print %T", items)
+	}
+
+	switch e.format {
+	case formatJSON:
+		enc := json.NewEncoder(e.out)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(items)
+	case formatNDJSON:
+		enc := json.NewEncoder(e.out)
+		for i := 0; i < v.Len(); i++ {
+			err := enc.Encode(v.Index(i).Interface())
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	columns := structColumns(v.Type().Elem())
+	rows := make([][]string, 0, v.Len())
+
+	for i := 0; i < v.Len(); i++ {
+		item := reflect.Indirect(v.Index(i))
+		row := make([]string, 0, len(columns))
+
+		for _, col := range columns {
+			row = append(row, formatValue(item.Field(col.index).Interface()))
+		}
+
+		rows = append(rows, row)
+	}
+
+	header := make([]string, 0, len(columns))
+	for _, col := range columns {
+		header = append(header, col.name)
+	}
+
+	return e.writeGrid(header, rows)
+}
+
+// writeRecords prints records that share the given columns.
+func (e *env) writeRecords(columns []string, records []map[string]interface{}) error {
+	switch e.format {
+	case formatJSON:
+		enc := json.NewEncoder(e.out)
+		enc.SetIndent("", "  ")
+
+		return enc.Encode(records)
+	case formatNDJSON:
+		enc := json.NewEncoder(e.out)
+		for _, record := range records {
+			err := enc.Encode(record)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	rows := make([][]string, 0, len(records))
+
+	for _, record := range records {
+		row := make([]string, 0, len(columns))
+		for _, col := range columns {
+			row = append(row, formatValue(record[col]))
+		}
+
+		rows = append(rows, row)
+	}
+
+	return e.writeGrid(columns, rows)
+}
+
+func (e *env) writeGrid(header []string, rows [][]string) error {
+	if e.format == formatCSV {
+		w := csv.NewWriter(e.out)
+
+		err := w.Write(header)
+		if err != nil {
+			return err
+		}
+
+		err = w.WriteAll(rows)
+		if err != nil {
+			return err
+		}
+
+		return w.Error()
+	}
+
+	w := tabwriter.NewWriter(e.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+
+	for _, row := range rows {
+		for i := range row {
+			row[i] = strings.NewReplacer("\t", " ", "\n", " ").Replace(row[i])
+		}
+
+		fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+
+	return w.Flush()
+}
+
+type structColumn struct {
+	name  string
+	index int
+}
+
+func structColumns(t reflect.Type) []structColumn {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	columns := make([]structColumn, 0, t.NumField())
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := field.Name
+
+		if tag, ok := field.Tag.Lookup("json"); ok {
+			tagName := strings.Split(tag, ",")[0]
+			if tagName == "-" {
+				continue
+			}
+
+			if tagName != "" {
+				name = tagName
+			}
+		}
+
+		columns = append(columns, structColumn{name: name, index: i})
+	}
+
+	return columns
+}
+
+func formatValue(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+
+	if t, ok := value.(gosmartis.FlexTime); ok {
+		if t.IsZero() {
+			return ""
+		}
+
+		return t.Format(time.RFC3339)
+	}
+
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct, reflect.Slice, reflect.Map, reflect.Array:
+		data, err := json.Marshal(v.Interface())
+		if err != nil {
+			return fmt.Sprint(v.Interface())
+		}
+
+		return string(data)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/zfullio/gosmartis"
+	"github.com/zfullio/gosmartis/recorder"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// reportArgs runs the report recorded in testdata/report.json.
+var reportArgs = []string{"report", "-project", "object_101", "-metrics", "leads", "-from", "2024-01-01", "-to", "2024-01-02"}
+
+func TestReportOutput(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: "table",
+			want: "metric  channel_id  day         leads\n" +
+				"leads   Search ads  2024-01-01  1.5\n" +
+				"leads               2024-01-02  2\n",
+		},
+		{
+			format: "csv",
+			want: "metric,channel_id,day,leads\n" +
+				"leads,Search\tads,2024-01-01,1.5\n" +
+				"leads,,2024-01-02,2\n",
+		},
+		{
+			format: "ndjson",
+			want: `{"channel_id":"Search\tads","day":"2024-01-01","leads":1.5,"metric":"leads"}` + "\n" +
+				`{"day":"2024-01-02","leads":2,"metric":"leads"}` + "\n",
+		},
+		{
+			format: "json",
+			want: "[\n" +
+				"  {\n" +
+				`    "channel_id": "Search\tads",` + "\n" +
+				`    "day": "2024-01-01",` + "\n" +
+				`    "leads": 1.5,` + "\n" +
+				`    "metric": "leads"` + "\n" +
+				"  },\n" +
+				"  {\n" +
+				`    "day": "2024-01-02",` + "\n" +
+				`    "leads": 2,` + "\n" +
+				`    "metric": "leads"` + "\n" +
+				"  }\n" +
+				"]\n",
+		},
+	}
+
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv(envConfig, "")
+	t.Setenv(envAPIKey, "test-key")
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			rec, err := recorder.New(filepath.Join("testdata", "report.json"), recorder.ModeReplay, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var out bytes.Buffer
+
+			err = run(append([]string{"-format", tt.format}, reportArgs...), &out, &http.Client{Transport: rec})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if out.String() != tt.want {
+				t.Errorf("output:\n%s\nwant:\n%s", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteItems(t *testing.T) {
+	type item struct {
+		ID      int               `json:"id"`
+		Title   string            `json:"title"`
+		Note    *string           `json:"note"`
+		Rate    float64           `json:"rate"`
+		Tags    []string          `json:"tags"`
+		Skipped string            `json:"-"`
+		Plain   bool              `json:",omitempty"`
+		Extra   map[string]string `json:"extra"`
+		hidden  int
+	}
+
+	note := "a\tb"
+	items := []item{
+		{ID: 1, Title: "First, one", Note: &note, Rate: 0.25, Tags: []string{"x"}, Skipped: "no", Plain: true, hidden: 1},
+		{ID: 2, Title: "Second", Rate: 1e6},
+	}
+
+	tests := []struct {
+		format format
+		want   string
+	}{
+		{
+			format: formatTable,
+			want: "id  title       note  rate     tags   Plain  extra\n" +
+				"1   First, one  a b   0.25     [\"x\"]  true   {}\n" +
+				"2   Second            1000000  null   false  null\n",
+		},
+		{
+			format: formatCSV,
+			want: "id,title,note,rate,tags,Plain,extra\n" +
+				"1,\"First, one\",a\tb,0.25,\"[\"\"x\"\"]\",true,{}\n" +
+				"2,Second,,1000000,null,false,null\n",
+		},
+	}
+
+	items[0].Extra = map[string]string{}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var out bytes.Buffer
+
+			e := &env{out: &out, format: tt.format}
+
+			err := e.writeItems(items)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if out.String() != tt.want {
+				t.Errorf("output:\n%s\nwant:\n%s", out.String(), tt.want)
+			}
+		})
+	}
+
+	err := (&env{out: &bytes.Buffer{}, format: formatTable}).writeItems(items[0])
+	if err == nil {
+		t.Error("writeItems accepts a non-slice")
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	f, err := parseFormat("CSV")
+	if err != nil || f != formatCSV {
+		t.Errorf("parseFormat(CSV) = %q, %v", f, err)
+	}
+
+	_, err = parseFormat("xml")
+	if err == nil {
+		t.Error("parseFormat accepts xml")
+	}
+}
+
+func TestProjectsOutput(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: "table",
+			want: "id   project     title  created_at            is_active  is_super_object  can_grouping_by_objects  project_fields\n" +
+				"101  object_101  Shop   2024-03-01T10:15:00Z  true       false            true                     null\n" +
+				"102  object_102  Blog                         false      false            false                    null\n",
+		},
+		{
+			format: "ndjson",
+			want: `{"id":101,"project":"object_101","title":"Shop","created_at":"2024-03-01T10:15:00Z","is_active":true,"is_super_object":false,"can_grouping_by_objects":true,"project_fields":null}` + "\n" +
+				`{"id":102,"project":"object_102","title":"Blog","created_at":null,"is_active":false,"is_super_object":false,"can_grouping_by_objects":false,"project_fields":null}` + "\n",
+		},
+	}
+
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv(envConfig, "")
+	t.Setenv(envAPIKey, "test-key")
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			rec, err := recorder.New(filepath.Join("testdata", "projects.json"), recorder.ModeReplay, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var out bytes.Buffer
+
+			err = run([]string{"-format", tt.format, "projects"}, &out, &http.Client{Transport: rec})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if out.String() != tt.want {
+				t.Errorf("output:\n%s\nwant:\n%s", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestCRMSchemaOutput(t *testing.T) {
+	want := "# CRM custom fields\n" +
+		"\n" +
+		"## Deal (group 2)\n" +
+		"\n" +
+		"| ID | Column | Title | Type | Element | Multiple | Filter |\n" +
+		"|---:|---|---|---|---|---|---|\n" +
+		"| 31 | `field_31` | Source \\| UTM | select | lead | no | 405 |\n" +
+		"\n" +
+		"## Ungrouped\n" +
+		"\n" +
+		"| ID | Column | Title | Type | Element | Multiple | Filter |\n" +
+		"|---:|---|---|---|---|---|---|\n" +
+		"| 32 | `field_32` | Phone | multitext | contact | yes |  |\n"
+
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv(envConfig, "")
+	t.Setenv(envAPIKey, "test-key")
+	t.Setenv(envCRMToken, "crm-token")
+
+	// The cassette only matches requests for all fields with "ids": [].
+	rec, err := recorder.New(filepath.Join("testdata", "crm_schema.json"), recorder.ModeReplay, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+
+	err = run([]string{"crm-schema", "-markdown"}, &out, &http.Client{Transport: rec})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if out.String() != want {
+		t.Errorf("output:\n%s\nwant:\n%s", out.String(), want)
+	}
+
+	t.Setenv(envCRMToken, "")
+
+	err = run([]string{"crm-schema"}, &bytes.Buffer{}, &http.Client{Transport: rec})
+	if !errors.Is(err, gosmartis.ErrEmptyCRMToken) {
+		t.Errorf("err without a CRM token = %v", err)
+	}
+}
@@ -0,0 +1,325 @@
+package gosmartis
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Layouts of date values returned by Smartis.
+const (
+	smartisDateLayout     = "2006-01-02"
+	smartisDateTimeLayout = "2006-01-02 15:04:05"
+)
+
+// ColumnKind is the value type inferred for a report column.
+type ColumnKind int
+
+const (
+	ColumnString ColumnKind = iota
+	ColumnInt
+	ColumnFloat
+	ColumnBool
+	ColumnDate
+	ColumnDateTime
+)
+
+func (k ColumnKind) String() string {
+	switch k {
+	case ColumnString:
+		return "string"
+	case ColumnInt:
+		return "int"
+	case ColumnFloat:
+		return "float"
+	case ColumnBool:
+		return "bool"
+	case ColumnDate:
+		return "date"
+	case ColumnDateTime:
+		return "datetime"
+	default:
+		return "ColumnKind(" + strconv.Itoa(int(k)) + ")"
+	}
+}
+
+// ColumnInfo describes a report column for typed exports.
+type ColumnInfo struct {
+	ID   string
+	Name string
+	Type CellType
+	Kind ColumnKind
+	// Nullable is set when some rows miss the column or hold nil.
+	Nullable bool
+	// Dimension is set for grouping keys and identifiers, as opposed to
+	// metric values.
+	Dimension bool
+}
+
+// ColumnInfos infers the columns of the report in the order of Columns.
+func (r *Report) ColumnInfos() []ColumnInfo {
+	return InferColumns(r.RowsMassive)
+}
+
+// InferColumns infers column types from the given rows.
+// Numbers become ColumnInt for integral dimension columns and ColumnFloat
+// otherwise, strings holding Smartis dates become ColumnDate or
+// ColumnDateTime, and columns with mixed value types fall back to ColumnString.
+// Columns without any non-nil value are nullable ColumnString columns that
+// are dimensions only when isDimension says so.
+func InferColumns(rows []Row) []ColumnInfo {
+	type stats struct {
+		info              ColumnInfo
+		seen              int
+		numbers, integral int
+		strings           int
+		dates, datetimes  int
+		bools, others     int
+	}
+
+	byID := make(map[string]*stats)
+
+	for _, row := range rows {
+		for _, cell := range row {
+			s, ok := byID[cell.ColumnID]
+			if !ok {
+				s = &stats{info: ColumnInfo{ID: cell.ColumnID, Type: cell.Type}}
+				byID[cell.ColumnID] = s
+			}
+
+			if cell.Name != "" {
+				s.info.Name = cell.Name
+			}
+
+			s.seen++
+
+			switch v := cell.Value.(type) {
+			case nil:
+				s.info.Nullable = true
+			case string:
+				s.strings++
+
+				if _, ok := ParseDate(v); ok {
+					s.dates++
+				} else if _, ok := ParseDateTime(v); ok {
+					s.datetimes++
+				}
+			case bool:
+				s.bools++
+			default:
+				f, ok := CellFloat(v)
+				if !ok {
+					s.others++
+
+					continue
+				}
+
+				s.numbers++
+
+				if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+					s.integral++
+				}
+			}
+		}
+	}
+
+	ids := make([]string, 0, len(byID))
+	types := make(map[string]CellType, len(byID))
+
+	for id, s := range byID {
+		ids = append(ids, id)
+		types[id] = s.info.Type
+	}
+
+	sortColumns(ids, types)
+
+	infos := make([]ColumnInfo, 0, len(ids))
+
+	for _, id := range ids {
+		s := byID[id]
+		info := s.info
+
+		if s.seen < len(rows) {
+			info.Nullable = true
+		}
+
+		info.Dimension = isDimension(info.ID, info.Type)
+		nonNull := s.numbers + s.strings + s.bools + s.others
+
+		switch {
+		case nonNull == 0:
+			info.Kind = ColumnString
+		case s.numbers == nonNull:
+			info.Kind = ColumnFloat
+			if info.Dimension && s.integral == s.numbers {
+				info.Kind = ColumnInt
+			}
+		case s.dates == nonNull:
+			info.Kind = ColumnDate
+		case s.datetimes == nonNull:
+			info.Kind = ColumnDateTime
+		case s.bools == nonNull:
+			info.Kind = ColumnBool
+		default:
+			info.Kind = ColumnString
+		}
+
+		// Text and date columns are grouping keys; a column without values,
+		// such as a metric on a quiet day, is not.
+		if nonNull > 0 && (info.Kind == ColumnString || info.Kind == ColumnDate || info.Kind == ColumnDateTime) {
+			info.Dimension = true
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos
+}
+
+// isDimension reports whether a column holds a grouping key or identifier.
+func isDimension(id string, t CellType) bool {
+	if t == CellTypeField || t == CellTypeFieldCfGroup {
+		return true
+	}
+
+	switch GroupBy(id) {
+	case GroupByAd, GroupByDay, GroupByPlacement, GroupByCampaign, GroupByObject:
+		return true
+	}
+
+	return id == "id" || strings.HasSuffix(id, "_id")
+}
+
+// ParseDate parses a Smartis date such as "2024-01-31".
+func ParseDate(s string) (time.Time, bool) {
+	if len(s) != len(smartisDateLayout) {
+		return time.Time{}, false
+	}
+
+	t, err := time.Parse(smartisDateLayout, s)
+
+	return t, err == nil
+}
+
+// ParseDateTime parses a Smartis date-time such as "2024-01-31 23:59:59".
+func ParseDateTime(s string) (time.Time, bool) {
+	if len(s) != len(smartisDateTimeLayout) {
+		return time.Time{}, false
+	}
+
+	t, err := time.Parse(smartisDateTimeLayout, s)
+
+	return t, err == nil
+}
+
+// CellFloat converts a numeric cell value to float64.
+func CellFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// CellInt converts an integral cell value to int64.
+// Numeric strings are accepted, since identifiers often arrive as strings.
+func CellInt(value interface{}) (int64, bool) {
+	switch v := value.(type) {
+	case int:
+		return int64(v), true
+	case int64:
+		return v, true
+	case string:
+		n, err := strconv.ParseInt(v, 10, 64)
+
+		return n, err == nil
+	case json.Number:
+		n, err := v.Int64()
+
+		return n, err == nil
+	}
+
+	f, ok := CellFloat(value)
+	if !ok || f != math.Trunc(f) {
+		return 0, false
+	}
+
+	return int64(f), true
+}
+
+// sortColumns puts system fields first, then CRM fields and CRM field
+// groups; within each kind columns are sorted by ID.
+func sortColumns(ids []string, types map[string]CellType) {
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := ids[i], ids[j]
+		if rankA, rankB := cellTypeRank(types[a]), cellTypeRank(types[b]); rankA != rankB {
+			return rankA < rankB
+		}
+
+		return naturalLess(a, b)
+	})
+}
+
+func cellTypeRank(t CellType) int {
+	switch t {
+	case CellTypeSystemField:
+		return 0
+	case CellTypeField:
+		return 1
+	case CellTypeFieldCfGroup:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// naturalLess orders "field_2" before "field_10".
+func naturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		da, db := leadingDigits(a), leadingDigits(b)
+
+		if da != "" && db != "" {
+			na, _ := strconv.Atoi(da)
+			nb, _ := strconv.Atoi(db)
+
+			if na != nb {
+				return na < nb
+			}
+
+			a, b = a[len(da):], b[len(db):]
+
+			continue
+		}
+
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+
+		a, b = a[1:], b[1:]
+	}
+
+	return len(a) < len(b)
+}
+
+func leadingDigits(s string) string {
+	i := 0
+	for i < len(s) && i < 18 && s[i] >= '0' && s[i] <= '9' {
+		i++
+	}
+
+	return s[:i]
+}
@@ -0,0 +1,59 @@
+package gosmartis
+
+import "testing"
+
+func TestInferColumns(t *testing.T) {
+	rows := []Row{
+		{
+			NewCell("day", "2024-01-01", ""),
+			NewCell("ad_id", 7.0, ""),
+			NewCell("utm_source", "yandex", ""),
+			NewCell("leads", nil, ""),
+			NewCell("cost", 1.5, ""),
+			NewCell("field_3", nil, "City"),
+		},
+		{
+			NewCell("day", "2024-01-02", ""),
+			NewCell("ad_id", 8.0, ""),
+			NewCell("leads", nil, ""),
+			NewCell("cost", 2.0, ""),
+		},
+	}
+
+	want := map[string]struct {
+		kind      ColumnKind
+		nullable  bool
+		dimension bool
+		name      string
+	}{
+		"day":        {ColumnDate, false, true, ""},
+		"ad_id":      {ColumnInt, false, true, ""},
+		"utm_source": {ColumnString, true, true, ""},
+		"leads":      {ColumnString, true, false, ""},
+		"cost":       {ColumnFloat, false, false, ""},
+		"field_3":    {ColumnString, true, true, "City"},
+	}
+
+	infos := InferColumns(rows)
+	if len(infos) != len(want) {
+		t.Fatalf("got %d columns, want %d", len(infos), len(want))
+	}
+
+	for _, info := range infos {
+		w, ok := want[info.ID]
+		if !ok {
+			t.Errorf("unexpected column %s", info.ID)
+
+			continue
+		}
+
+		if info.Kind != w.kind || info.Nullable != w.nullable || info.Dimension != w.dimension || info.Name != w.name {
+			t.Errorf("%s = kind %s nullable %v dimension %v name %q, want kind %s nullable %v dimension %v name %q",
+				info.ID, info.Kind, info.Nullable, info.Dimension, info.Name, w.kind, w.nullable, w.dimension, w.name)
+		}
+	}
+
+	if infos[len(infos)-1].ID != "field_3" {
+		t.Errorf("CRM field column is not last: %s", infos[len(infos)-1].ID)
+	}
+}
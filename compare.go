@@ -0,0 +1,274 @@
+package gosmartis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ComparisonMetric is the metric of the report built by
+// AttributionComparison.Report.
+const ComparisonMetric = "attribution_comparison"
+
+// ComparedColumn is a measure column compared across attribution models.
+type ComparedColumn struct {
+	// ID is the metric code, or "<metric>.<column>" when the measure is
+	// not named after its metric.
+	ID     string
+	Metric string
+	Column string
+	// Totals are the column sums per model.
+	Totals map[AttributionModel]float64
+}
+
+type ComparisonRow struct {
+	Key map[string]interface{}
+	// Values holds the value per ComparedColumn.ID and model. Missing rows
+	// count as zero.
+	Values map[string]map[AttributionModel]float64
+}
+
+// AttributionComparison is the same report fetched with several attribution
+// models, merged on its dimension columns. The first model is the baseline
+// of share deltas.
+type AttributionComparison struct {
+	Models     []AttributionModel
+	KeyColumns []string
+	Columns    []ComparedColumn
+	Rows       []ComparisonRow
+}
+
+// CompareAttributions runs payload once per model and merges the results.
+// Requests run concurrently and respect Client.Limiter.
+func (c *Client) CompareAttributions(ctx context.Context, payload Payload, models ...AttributionModel) (cmp *AttributionComparison, err error) {
+	ctx, end := c.startCall(ctx, Call{Method: "CompareAttributions", Payload: payload})
+	defer func() {
+		rows := 0
+		if cmp != nil {
+			rows = len(cmp.Rows)
+		}
+
+		end(rows, err)
+	}()
+
+	return CompareAttributions(ctx, c, payload, models...)
+}
+
+// CompareAttributions runs payload once per model through api, at most
+// maxConcurrentRequests at a time, and merges the results. A model without
+// data contributes no rows; any other error fails the comparison.
+func CompareAttributions(ctx context.Context, api API, payload Payload, models ...AttributionModel) (*AttributionComparison, error) {
+	err := checkModels(models)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([][]*Report, len(models))
+
+	errs := forEach(ctx, len(models), func(ctx context.Context, i int) error {
+		p := payload
+		p.Attribution.ModelID = models[i]
+
+		reports, err := api.GetReport(ctx, p)
+		if errors.Is(err, ErrNoReportData) {
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("attribution model %d: %w", models[i], err)
+		}
+
+		results[i] = reports
+
+		return nil
+	})
+
+	err = errors.Join(errs...)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewAttributionComparison(models, results)
+}
+
+func checkModels(models []AttributionModel) error {
+	if len(models) == 0 {
+		return errors.New("no attribution models to compare")
+	}
+
+	seen := make(map[AttributionModel]bool, len(models))
+
+	for _, model := range models {
+		if model < 1 {
+			return fmt.Errorf("attribution model %d is unknown", model)
+		}
+
+		if seen[model] {
+			return fmt.Errorf("attribution model %d is listed twice", model)
+		}
+
+		seen[model] = true
+	}
+
+	return nil
+}
+
+// NewAttributionComparison merges reports fetched per model; reports[i]
+// belongs to models[i].
+func NewAttributionComparison(models []AttributionModel, reports [][]*Report) (*AttributionComparison, error) {
+	err := checkModels(models)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(reports) != len(models) {
+		return nil, fmt.Errorf("got reports for %d models, want %d", len(reports), len(models))
+	}
+
+	var all []Row
+
+	byMetric := make(map[string][]Row)
+
+	for _, modelReports := range reports {
+		for _, report := range modelReports {
+			all = append(all, report.RowsMassive...)
+			byMetric[report.Metric] = append(byMetric[report.Metric], report.RowsMassive...)
+		}
+	}
+
+	result := &AttributionComparison{Models: models}
+
+	for _, col := range InferColumns(all) {
+		if col.Dimension {
+			result.KeyColumns = append(result.KeyColumns, col.ID)
+		}
+	}
+
+	metrics := make([]string, 0, len(byMetric))
+	for metric := range byMetric {
+		metrics = append(metrics, metric)
+	}
+
+	sort.Strings(metrics)
+
+	for _, metric := range metrics {
+		for _, col := range InferColumns(byMetric[metric]) {
+			if col.Dimension || (col.Kind != ColumnInt && col.Kind != ColumnFloat) {
+				continue
+			}
+
+			id := metric
+			if col.ID != metric {
+				id = metric + "." + col.ID
+			}
+
+			result.Columns = append(result.Columns, ComparedColumn{
+				ID:     id,
+				Metric: metric,
+				Column: col.ID,
+				Totals: make(map[AttributionModel]float64, len(models)),
+			})
+		}
+	}
+
+	index := make(map[string]*ComparisonRow)
+
+	for i, model := range models {
+		for _, report := range reports[i] {
+			for _, row := range report.RowsMassive {
+				key := keyString(row, result.KeyColumns)
+
+				merged, ok := index[key]
+				if !ok {
+					merged = &ComparisonRow{
+						Key:    rowKey(row, result.KeyColumns),
+						Values: make(map[string]map[AttributionModel]float64),
+					}
+					index[key] = merged
+				}
+
+				for _, col := range result.Columns {
+					if col.Metric != report.Metric {
+						continue
+					}
+
+					value, _ := CellFloat(row.Value(col.Column))
+
+					if merged.Values[col.ID] == nil {
+						merged.Values[col.ID] = make(map[AttributionModel]float64, len(models))
+					}
+
+					merged.Values[col.ID][model] += value
+					col.Totals[model] += value
+				}
+			}
+		}
+	}
+
+	keys := make([]string, 0, len(index))
+	for key := range index {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		result.Rows = append(result.Rows, *index[key])
+	}
+
+	return result, nil
+}
+
+// Share returns the part of the model's column total that falls on row.
+func (c *AttributionComparison) Share(row ComparisonRow, column ComparedColumn, model AttributionModel) float64 {
+	total := column.Totals[model]
+	if total == 0 {
+		return 0
+	}
+
+	return row.Values[column.ID][model] / total
+}
+
+// ShareDelta returns how much the share of row under model differs from
+// its share under the baseline model.
+func (c *AttributionComparison) ShareDelta(row ComparisonRow, column ComparedColumn, model AttributionModel) float64 {
+	return c.Share(row, column, model) - c.Share(row, column, c.Models[0])
+}
+
+// Report flattens the comparison into a single report. Per column and model
+// it holds the value as "<id>_model_<model>", its share of the total as
+// "..._share" and, for all but the baseline model, "..._share_delta".
+func (c *AttributionComparison) Report() *Report {
+	report := &Report{
+		Metric:      ComparisonMetric,
+		RowsMassive: make([]Row, 0, len(c.Rows)),
+	}
+
+	for _, row := range c.Rows {
+		cells := make(Row, 0, len(c.KeyColumns)+len(c.Columns)*len(c.Models)*3)
+
+		for _, key := range c.KeyColumns {
+			cells = append(cells, NewCell(key, row.Key[key], ""))
+		}
+
+		for _, col := range c.Columns {
+			for i, model := range c.Models {
+				id := fmt.Sprintf("%s_model_%d", col.ID, model)
+
+				cells = append(cells,
+					NewCell(id, row.Values[col.ID][model], ""),
+					NewCell(id+"_share", c.Share(row, col, model), ""),
+				)
+
+				if i > 0 {
+					cells = append(cells, NewCell(id+"_share_delta", c.ShareDelta(row, col, model), ""))
+				}
+			}
+		}
+
+		report.RowsMassive = append(report.RowsMassive, cells)
+	}
+
+	return report
+}
@@ -0,0 +1,239 @@
+package gosmartis_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/zfullio/gosmartis"
+	"github.com/zfullio/gosmartis/mock"
+)
+
+func channelRow(channel float64, cells ...*gosmartis.Cell) gosmartis.Row {
+	return append(gosmartis.Row{
+		gosmartis.NewCell("day", "2024-01-01", ""),
+		gosmartis.NewCell("channel_id", channel, ""),
+	}, cells...)
+}
+
+// compareAPI answers GetReport with the reports or error set for the
+// requested attribution model and records the models requested.
+type compareAPI struct {
+	mock.APIMock
+
+	mu        sync.Mutex
+	requested []gosmartis.AttributionModel
+}
+
+func newCompareAPI(reports map[gosmartis.AttributionModel][]*gosmartis.Report, errs map[gosmartis.AttributionModel]error) *compareAPI {
+	api := &compareAPI{}
+	api.GetReportFunc = func(_ context.Context, payload gosmartis.Payload) ([]*gosmartis.Report, error) {
+		api.mu.Lock()
+		api.requested = append(api.requested, payload.Attribution.ModelID)
+		api.mu.Unlock()
+
+		if err := errs[payload.Attribution.ModelID]; err != nil {
+			return nil, err
+		}
+
+		return reports[payload.Attribution.ModelID], nil
+	}
+
+	return api
+}
+
+func TestCompareAttributions(t *testing.T) {
+	last, first, linear := gosmartis.AttributionModelLastClick, gosmartis.AttributionModelFirstClick, gosmartis.AttributionModelLinear
+
+	reports := map[gosmartis.AttributionModel][]*gosmartis.Report{
+		last: {
+			{Metric: "leads", RowsMassive: []gosmartis.Row{
+				channelRow(1, gosmartis.NewCell("leads", 6.0, "")),
+				channelRow(2, gosmartis.NewCell("leads", 4.0, "")),
+			}},
+			{Metric: "cost", RowsMassive: []gosmartis.Row{
+				channelRow(1, gosmartis.NewCell("spend", 100.0, "")),
+			}},
+		},
+		first: {
+			{Metric: "leads", RowsMassive: []gosmartis.Row{
+				channelRow(1, gosmartis.NewCell("leads", 2.0, "")),
+				channelRow(2, gosmartis.NewCell("leads", 3.0, "")),
+				channelRow(2, gosmartis.NewCell("leads", 5.0, "")),
+			}},
+		},
+	}
+
+	// Values are per channel 1 and 2.
+	type values struct {
+		value, share, delta [2]float64
+	}
+
+	tests := []struct {
+		name    string
+		models  []gosmartis.AttributionModel
+		errs    map[gosmartis.AttributionModel]error
+		columns []string
+		want    map[string]map[gosmartis.AttributionModel]values
+		wantErr error
+	}{
+		{
+			name:    "two models",
+			models:  []gosmartis.AttributionModel{last, first},
+			columns: []string{"cost.spend", "leads"},
+			want: map[string]map[gosmartis.AttributionModel]values{
+				"leads": {
+					last:  {value: [2]float64{6, 4}, share: [2]float64{0.6, 0.4}},
+					first: {value: [2]float64{2, 8}, share: [2]float64{0.2, 0.8}, delta: [2]float64{-0.4, 0.4}},
+				},
+				"cost.spend": {
+					last:  {value: [2]float64{100, 0}, share: [2]float64{1, 0}},
+					first: {delta: [2]float64{-1, 0}},
+				},
+			},
+		},
+		{
+			name:    "model without data",
+			models:  []gosmartis.AttributionModel{first, linear},
+			errs:    map[gosmartis.AttributionModel]error{linear: gosmartis.ErrNoReportData},
+			columns: []string{"leads"},
+			want: map[string]map[gosmartis.AttributionModel]values{
+				"leads": {
+					first:  {value: [2]float64{2, 8}, share: [2]float64{0.2, 0.8}},
+					linear: {delta: [2]float64{-0.2, -0.8}},
+				},
+			},
+		},
+		{
+			name:    "failing model",
+			models:  []gosmartis.AttributionModel{last, first},
+			errs:    map[gosmartis.AttributionModel]error{first: errors.New("boom")},
+			wantErr: errors.New("attribution model 2: boom"),
+		},
+		{
+			name:    "duplicate model",
+			models:  []gosmartis.AttributionModel{last, first, last},
+			wantErr: errors.New("attribution model 1 is listed twice"),
+		},
+		{
+			name:    "unknown model",
+			models:  []gosmartis.AttributionModel{last, 0},
+			wantErr: errors.New("attribution model 0 is unknown"),
+		},
+		{
+			name:    "no models",
+			wantErr: errors.New("no attribution models to compare"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := newCompareAPI(reports, tt.errs)
+
+			cmp, err := gosmartis.CompareAttributions(context.Background(), api, gosmartis.Payload{Project: "object_1"}, tt.models...)
+
+			if tt.wantErr != nil {
+				if err == nil || err.Error() != tt.wantErr.Error() {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(api.requested) != len(tt.models) {
+				t.Errorf("requested models %v, want one request per model", api.requested)
+			}
+
+			if len(cmp.KeyColumns) != 2 || cmp.KeyColumns[0] != "channel_id" || cmp.KeyColumns[1] != "day" {
+				t.Errorf("key columns = %v", cmp.KeyColumns)
+			}
+
+			if len(cmp.Columns) != len(tt.columns) {
+				t.Fatalf("columns = %+v, want %v", cmp.Columns, tt.columns)
+			}
+
+			if len(cmp.Rows) != 2 {
+				t.Fatalf("rows = %+v", cmp.Rows)
+			}
+
+			for i, col := range cmp.Columns {
+				if col.ID != tt.columns[i] {
+					t.Errorf("column %d = %s, want %s", i, col.ID, tt.columns[i])
+				}
+
+				for model, want := range tt.want[col.ID] {
+					for r, row := range cmp.Rows {
+						value := row.Values[col.ID][model]
+						share := cmp.Share(row, col, model)
+						delta := cmp.ShareDelta(row, col, model)
+
+						if !near(value, want.value[r]) || !near(share, want.share[r]) || !near(delta, want.delta[r]) {
+							t.Errorf("%s model %d channel %v: value %v share %v delta %v, want %v %v %v",
+								col.ID, model, row.Key["channel_id"], value, share, delta, want.value[r], want.share[r], want.delta[r])
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestComparisonReport(t *testing.T) {
+	last, first := gosmartis.AttributionModelLastClick, gosmartis.AttributionModelFirstClick
+
+	cmp, err := gosmartis.NewAttributionComparison([]gosmartis.AttributionModel{last, first}, [][]*gosmartis.Report{
+		{{Metric: "leads", RowsMassive: []gosmartis.Row{channelRow(1, gosmartis.NewCell("leads", 3.0, ""))}}},
+		{{Metric: "leads", RowsMassive: []gosmartis.Row{channelRow(1, gosmartis.NewCell("leads", 1.0, "")), channelRow(2, gosmartis.NewCell("leads", 1.0, ""))}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := cmp.Report()
+	if report.Metric != gosmartis.ComparisonMetric || len(report.RowsMassive) != 2 {
+		t.Fatalf("report = %+v", report)
+	}
+
+	want := map[string]float64{
+		"leads_model_1":             3,
+		"leads_model_1_share":       1,
+		"leads_model_2":             1,
+		"leads_model_2_share":       0.5,
+		"leads_model_2_share_delta": -0.5,
+	}
+
+	row := report.RowsMassive[0]
+	if n := len(row); n != 2+len(want) {
+		t.Errorf("row has %d cells: %v", n, row)
+	}
+
+	if row.Value("channel_id") != 1.0 || row.Value("day") != "2024-01-01" {
+		t.Errorf("key cells = %v, %v", row.Value("channel_id"), row.Value("day"))
+	}
+
+	for id, value := range want {
+		got, ok := gosmartis.CellFloat(row.Value(id))
+		if !ok || !near(got, value) {
+			t.Errorf("%s = %v, want %v", id, row.Value(id), value)
+		}
+	}
+
+	if row.Value("leads_model_1_share_delta") != nil {
+		t.Error("the baseline model has a share delta")
+	}
+
+	_, err = gosmartis.NewAttributionComparison([]gosmartis.AttributionModel{last, first}, nil)
+	if err == nil {
+		t.Error("reports for fewer models are accepted")
+	}
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
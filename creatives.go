@@ -0,0 +1,288 @@
+package gosmartis
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// CreativePerformance is an ad joined with its report values.
+type CreativePerformance struct {
+	Ad  Ad
+	UTM UTM
+	// Values holds the summed measures of the ad, keyed by metric code or by
+	// "<metric>.<column>" for measures not named after their metric.
+	Values map[string]float64
+}
+
+// GetCreativePerformance runs payload grouped by GroupByAd and joins the
+// rows with the ads from GetAds.
+func GetCreativePerformance(ctx context.Context, api API, payload Payload) ([]CreativePerformance, error) {
+	payload.GroupBy = GroupByAd
+
+	reports, err := api.GetReport(ctx, payload)
+	if errors.Is(err, ErrNoReportData) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[int]bool)
+
+	var ids []int
+
+	for _, report := range reports {
+		for _, row := range report.RowsMassive {
+			id, ok := CellInt(row.Value(string(GroupByAd)))
+			if ok && id != 0 && !seen[int(id)] {
+				seen[int(id)] = true
+				ids = append(ids, int(id))
+			}
+		}
+	}
+
+	var ads []Ad
+
+	if len(ids) > 0 {
+		sort.Ints(ids)
+
+		ads, err = api.GetAds(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return JoinAds(reports, ads), nil
+}
+
+// JoinAds sums the measures of ad-level report rows per ad and attaches the
+// ads. Ads missing from ads are returned with only ID set.
+func JoinAds(reports []*Report, ads []Ad) []CreativePerformance {
+	byID := make(map[int]Ad, len(ads))
+	for _, ad := range ads {
+		byID[ad.ID] = ad
+	}
+
+	ids, values := sumByID(reports, string(GroupByAd))
+
+	result := make([]CreativePerformance, 0, len(ids))
+
+	for _, id := range ids {
+		ad, ok := byID[id]
+		if !ok {
+			ad = Ad{ID: id}
+		}
+
+		result = append(result, CreativePerformance{Ad: ad, UTM: ad.UTM(), Values: values[id]})
+	}
+
+	return result
+}
+
+// sumByID sums the measures of report rows per value of the idColumn
+// column. Measures are keyed by metric code, or by "<metric>.<column>" when
+// not named after their metric. IDs are returned in ascending order.
+func sumByID(reports []*Report, idColumn string) ([]int, map[int]map[string]float64) {
+	values := make(map[int]map[string]float64)
+
+	for _, report := range reports {
+		var measures []string
+
+		for _, col := range report.ColumnInfos() {
+			if !col.Dimension && (col.Kind == ColumnInt || col.Kind == ColumnFloat) {
+				measures = append(measures, col.ID)
+			}
+		}
+
+		for _, row := range report.RowsMassive {
+			id, ok := CellInt(row.Value(idColumn))
+			if !ok {
+				continue
+			}
+
+			sums, ok := values[int(id)]
+			if !ok {
+				sums = make(map[string]float64)
+				values[int(id)] = sums
+			}
+
+			for _, col := range measures {
+				value, _ := CellFloat(row.Value(col))
+
+				key := report.Metric
+				if col != report.Metric {
+					key = report.Metric + "." + col
+				}
+
+				sums[key] += value
+			}
+		}
+	}
+
+	ids := make([]int, 0, len(values))
+	for id := range values {
+		ids = append(ids, id)
+	}
+
+	sort.Ints(ids)
+
+	return ids, values
+}
+
+// CreativeReport turns creatives into a report with one row per ad, so it
+// can be written with the report exporters.
+func CreativeReport(creatives []CreativePerformance) *Report {
+	report := &Report{Metric: "creatives", RowsMassive: make([]Row, 0, len(creatives))}
+
+	for _, c := range creatives {
+		row := Row{
+			NewCell(string(GroupByAd), c.Ad.ID, ""),
+			NewCell("campaign_id", c.Ad.CampaignID, ""),
+			NewCell("placement_id", c.Ad.PlacementID, ""),
+			NewCell("type", c.Ad.Type, ""),
+			NewCell("device", strings.Join(c.Ad.Devices(), ","), ""),
+			NewCell("title", c.Ad.Title, ""),
+			NewCell("text", c.Ad.Text, ""),
+			NewCell("href", c.Ad.HrefURL(), ""),
+			NewCell(UTMSource, c.UTM.Source, ""),
+			NewCell(UTMMedium, c.UTM.Medium, ""),
+			NewCell(UTMCampaign, c.UTM.Campaign, ""),
+			NewCell(UTMContent, c.UTM.Content, ""),
+			NewCell(UTMTerm, c.UTM.Term, ""),
+		}
+
+		for _, key := range sortedValueKeys(c.Values) {
+			row = append(row, NewCell(key, c.Values[key], ""))
+		}
+
+		report.RowsMassive = append(report.RowsMassive, row)
+	}
+
+	return report
+}
+
+// CreativeGroup sums the values of the creatives sharing a key.
+type CreativeGroup struct {
+	Key    string
+	Ads    int
+	Values map[string]float64
+}
+
+// GroupCreatives groups creatives by key, ordered by key.
+func GroupCreatives(creatives []CreativePerformance, key func(CreativePerformance) string) []CreativeGroup {
+	index := make(map[string]*CreativeGroup)
+
+	for _, c := range creatives {
+		k := key(c)
+
+		group, ok := index[k]
+		if !ok {
+			group = &CreativeGroup{Key: k, Values: make(map[string]float64)}
+			index[k] = group
+		}
+
+		group.Ads++
+
+		for name, value := range c.Values {
+			group.Values[name] += value
+		}
+	}
+
+	result := make([]CreativeGroup, 0, len(index))
+	for _, group := range index {
+		result = append(result, *group)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+
+	return result
+}
+
+// ByType groups creatives by Ad.Type.
+func ByType(c CreativePerformance) string {
+	return c.Ad.Type
+}
+
+// ByDevice groups creatives by Ad.Devices, joined with commas for ads
+// targeting several devices.
+func ByDevice(c CreativePerformance) string {
+	return strings.Join(c.Ad.Devices(), ",")
+}
+
+// DuplicateCreative is a creative used by ads of more than one campaign.
+type DuplicateCreative struct {
+	Ads       []Ad
+	Campaigns []int
+}
+
+// DuplicateCreatives finds ads with the same type, texts and link, ignoring
+// case, spacing and UTM parameters, that belong to different campaigns.
+func DuplicateCreatives(ads []Ad) []DuplicateCreative {
+	groups := make(map[string][]Ad)
+
+	var order []string
+
+	for _, ad := range ads {
+		key := creativeFingerprint(ad)
+
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+
+		groups[key] = append(groups[key], ad)
+	}
+
+	var result []DuplicateCreative
+
+	for _, key := range order {
+		group := groups[key]
+
+		seen := make(map[int]bool)
+
+		var campaigns []int
+
+		for _, ad := range group {
+			if !seen[ad.CampaignID] {
+				seen[ad.CampaignID] = true
+				campaigns = append(campaigns, ad.CampaignID)
+			}
+		}
+
+		if len(campaigns) < 2 {
+			continue
+		}
+
+		sort.Ints(campaigns)
+		result = append(result, DuplicateCreative{Ads: group, Campaigns: campaigns})
+	}
+
+	return result
+}
+
+func creativeFingerprint(ad Ad) string {
+	text2 := ""
+	if ad.Text2 != nil {
+		text2 = *ad.Text2
+	}
+
+	parts := []string{ad.Type, ad.Title, ad.Text, ad.Text1, text2, landingKey(ad)}
+	for i, part := range parts {
+		parts[i] = strings.ToLower(strings.Join(strings.Fields(part), " "))
+	}
+
+	return strings.Join(parts, "\x00")
+}
+
+func sortedValueKeys(values map[string]float64) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,213 @@
+package gosmartis
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// testAds decodes ads from their API JSON.
+func testAds(t *testing.T, data string) []Ad {
+	t.Helper()
+
+	var ads []Ad
+
+	err := json.Unmarshal([]byte(data), &ads)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ads
+}
+
+func TestGetCreativePerformance(t *testing.T) {
+	api := &fakeAPI{handle: func(endpoint string, body map[string]interface{}) (int, string) {
+		switch endpoint {
+		case GetReports.endpoint:
+			return http.StatusOK, `{"reports": {
+				"leads": [
+					{"ad_id": 12, "day": "2024-01-01", "leads": 2, "conversions": 1},
+					{"ad_id": 11, "day": "2024-01-01", "leads": 1, "conversions": 0},
+					{"ad_id": 12, "day": "2024-01-02", "leads": 3, "conversions": 2},
+					{"ad_id": null, "day": "2024-01-02", "leads": 9, "conversions": 9}
+				],
+				"cost": [
+					{"ad_id": 12, "day": "2024-01-01", "cost": 10.5},
+					{"ad_id": 13, "day": "2024-01-01", "cost": 4}
+				]
+			}}`
+		case GetAds.endpoint:
+			return http.StatusOK, `{"ads": [
+				{"id": 11, "campaign_id": 1, "type": "text", "title": "Sale", "href": "https://shop.example.com/?utm_source=yandex&utm_campaign=sale"},
+				{"id": 12, "campaign_id": 2, "type": "image", "title": "Summer"}
+			]}`
+		default:
+			return http.StatusNotFound, ``
+		}
+	}}
+
+	payload := Payload{Project: "object_1", Metrics: []string{"leads", "cost"}, GroupBy: GroupByDay}
+
+	creatives, err := GetCreativePerformance(context.Background(), api.client(), payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, req := range api.requests {
+		switch req.endpoint {
+		case GetReports.endpoint:
+			if req.body["groupBy"] != string(GroupByAd) {
+				t.Errorf("report grouped by %v", req.body["groupBy"])
+			}
+		case GetAds.endpoint:
+			if !reflect.DeepEqual(req.body["ids"], []interface{}{11.0, 12.0, 13.0}) {
+				t.Errorf("requested ads %v, want [11 12 13]", req.body["ids"])
+			}
+		}
+	}
+
+	if len(creatives) != 3 {
+		t.Fatalf("creatives = %+v", creatives)
+	}
+
+	want := []struct {
+		id     int
+		title  string
+		source string
+		values map[string]float64
+	}{
+		{11, "Sale", "yandex", map[string]float64{"leads": 1, "leads.conversions": 0}},
+		{12, "Summer", "", map[string]float64{"leads": 5, "leads.conversions": 3, "cost": 10.5}},
+		// Ad 13 is missing from GetAds.
+		{13, "", "", map[string]float64{"cost": 4}},
+	}
+
+	for i, w := range want {
+		c := creatives[i]
+
+		if c.Ad.ID != w.id || c.Ad.Title != w.title || c.UTM.Source != w.source {
+			t.Errorf("creative %d = ad %d %q, utm_source %q", i, c.Ad.ID, c.Ad.Title, c.UTM.Source)
+		}
+
+		if !reflect.DeepEqual(c.Values, w.values) {
+			t.Errorf("ad %d values = %v, want %v", w.id, c.Values, w.values)
+		}
+	}
+}
+
+func TestGetCreativePerformanceNoData(t *testing.T) {
+	api := &fakeAPI{handle: func(string, map[string]interface{}) (int, string) {
+		return http.StatusOK, `{"reports": {}}`
+	}}
+
+	creatives, err := GetCreativePerformance(context.Background(), api.client(), Payload{Project: "object_1"})
+	if err != nil || creatives != nil {
+		t.Errorf("creatives = %v, %v", creatives, err)
+	}
+
+	if n := api.count(GetAds.endpoint); n != 0 {
+		t.Errorf("GetAds called %d times without ad rows", n)
+	}
+}
+
+func TestSumByID(t *testing.T) {
+	reports := []*Report{
+		{Metric: "leads", RowsMassive: []Row{
+			{NewCell("campaign_id", 2.0, ""), NewCell("leads", 1.0, ""), NewCell("title", "x", "")},
+			{NewCell("campaign_id", "2", ""), NewCell("leads", 2.0, ""), NewCell("title", "y", "")},
+			{NewCell("campaign_id", 1.0, ""), NewCell("leads", nil, ""), NewCell("title", "z", "")},
+			{NewCell("campaign_id", "n/a", ""), NewCell("leads", 5.0, ""), NewCell("title", "w", "")},
+		}},
+	}
+
+	ids, values := sumByID(reports, "campaign_id")
+
+	if !reflect.DeepEqual(ids, []int{1, 2}) {
+		t.Errorf("ids = %v", ids)
+	}
+
+	want := map[int]map[string]float64{1: {"leads": 0}, 2: {"leads": 3}}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("values = %v, want %v", values, want)
+	}
+}
+
+func TestJoinAdsAndCreativeReport(t *testing.T) {
+	ads := testAds(t, `[{"id": 7, "campaign_id": 3, "placement_id": 4, "type": "text", "title": "Sale",
+		"text": "Up to 50%", "device": "mobile", "href": "https://shop.example.com/?utm_source=vk&utm_medium=cpc"}]`)
+
+	reports := []*Report{{Metric: "cost", RowsMassive: []Row{
+		{NewCell("ad_id", 7.0, ""), NewCell("spend", 2.5, "")},
+		{NewCell("ad_id", 7.0, ""), NewCell("spend", 1.5, "")},
+		{NewCell("ad_id", 8.0, ""), NewCell("spend", 1.0, "")},
+	}}}
+
+	creatives := JoinAds(reports, ads)
+	if len(creatives) != 2 || creatives[0].Ad.Title != "Sale" || creatives[1].Ad.ID != 8 || creatives[1].Ad.Title != "" {
+		t.Fatalf("creatives = %+v", creatives)
+	}
+
+	if got := creatives[0].Values["cost.spend"]; got != 4 {
+		t.Errorf("cost.spend = %v, want 4", got)
+	}
+
+	report := CreativeReport(creatives)
+	if len(report.RowsMassive) != 2 {
+		t.Fatalf("report rows = %d", len(report.RowsMassive))
+	}
+
+	row := report.RowsMassive[0]
+
+	want := map[string]interface{}{
+		"ad_id":        7,
+		"campaign_id":  3,
+		"placement_id": 4,
+		"type":         "text",
+		"device":       "mobile",
+		"title":        "Sale",
+		"text":         "Up to 50%",
+		"href":         "https://shop.example.com/?utm_source=vk&utm_medium=cpc",
+		"utm_source":   "vk",
+		"utm_medium":   "cpc",
+		"utm_campaign": "",
+		"cost.spend":   4.0,
+	}
+
+	for id, value := range want {
+		if got := row.Value(id); got != value {
+			t.Errorf("%s = %#v, want %#v", id, got, value)
+		}
+	}
+
+	if last := row[len(row)-1]; last.ColumnID != "cost.spend" {
+		t.Errorf("values are not the last cells: %v", row)
+	}
+}
+
+func TestDuplicateCreatives(t *testing.T) {
+	ads := testAds(t, `[
+		{"id": 1, "campaign_id": 10, "type": "text", "title": "Summer  sale", "text": "Up to 50%",
+			"href": "https://shop.example.com/sale?utm_source=yandex&color=red"},
+		{"id": 2, "campaign_id": 20, "type": "text", "title": " summer sale", "text": "up to 50%",
+			"href": "https://shop.example.com/sale?color=red&utm_source=vk&utm_campaign=2"},
+		{"id": 3, "campaign_id": 10, "type": "text", "title": "Winter sale", "text": "Up to 30%",
+			"href": "https://shop.example.com/winter"},
+		{"id": 4, "campaign_id": 10, "type": "text", "title": "Winter sale", "text": "Up to 30%",
+			"href": "https://shop.example.com/winter?utm_content=b"},
+		{"id": 5, "campaign_id": 30, "type": "text", "title": "Summer sale", "text": "Up to 50%",
+			"href": "https://shop.example.com/other"}
+	]`)
+
+	duplicates := DuplicateCreatives(ads)
+
+	if len(duplicates) != 1 {
+		t.Fatalf("duplicates = %+v", duplicates)
+	}
+
+	dup := duplicates[0]
+	if !reflect.DeepEqual(dup.Campaigns, []int{10, 20}) || len(dup.Ads) != 2 || dup.Ads[0].ID != 1 || dup.Ads[1].ID != 2 {
+		t.Errorf("duplicate = campaigns %v, %d ads", dup.Campaigns, len(dup.Ads))
+	}
+}
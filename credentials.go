@@ -0,0 +1,307 @@
+package gosmartis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const redacted = "REDACTED"
+
+// Secret is a credential that does not print. fmt, encoding/json and
+// log/slog all render it as REDACTED; use Reveal to get the value.
+type Secret string
+
+func (s Secret) Reveal() string {
+	return string(s)
+}
+
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+
+	return redacted
+}
+
+func (s Secret) GoString() string {
+	return `"` + s.String() + `"`
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(s.String())
+}
+
+// Credentials authenticate requests: APIKey every request and CRMToken the
+// CRM custom field requests.
+type Credentials struct {
+	APIKey   Secret `json:"api_key"`
+	CRMToken Secret `json:"crm_token"`
+}
+
+// merge fills the empty fields of c from other.
+func (c Credentials) merge(other Credentials) Credentials {
+	if c.APIKey == "" {
+		c.APIKey = other.APIKey
+	}
+
+	if c.CRMToken == "" {
+		c.CRMToken = other.CRMToken
+	}
+
+	return c
+}
+
+type credentialsKey struct{}
+
+// WithCredentials returns a context whose requests use creds instead of the
+// client's credentials. Empty fields fall back to the client.
+func WithCredentials(ctx context.Context, creds Credentials) context.Context {
+	if parent, ok := CredentialsFromContext(ctx); ok {
+		creds = creds.merge(parent)
+	}
+
+	return context.WithValue(ctx, credentialsKey{}, creds)
+}
+
+// CredentialsFromContext returns the credentials set by WithCredentials.
+func CredentialsFromContext(ctx context.Context) (Credentials, bool) {
+	creds, ok := ctx.Value(credentialsKey{}).(Credentials)
+
+	return creds, ok
+}
+
+// CredentialProvider supplies credentials per request, so that rotated keys
+// are picked up without recreating the client.
+type CredentialProvider interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// CredentialProviderFunc adapts a function to CredentialProvider.
+type CredentialProviderFunc func(ctx context.Context) (Credentials, error)
+
+func (f CredentialProviderFunc) Credentials(ctx context.Context) (Credentials, error) {
+	return f(ctx)
+}
+
+// StaticCredentials always returns the same credentials.
+func StaticCredentials(apiKey, crmToken string) CredentialProvider {
+	creds := Credentials{APIKey: Secret(apiKey), CRMToken: Secret(crmToken)}
+
+	return CredentialProviderFunc(func(context.Context) (Credentials, error) {
+		return creds, nil
+	})
+}
+
+// EnvCredentials reads the credentials from environment variables on every
+// request. Empty names default to SMARTIS_API_KEY and SMARTIS_CRM_TOKEN.
+func EnvCredentials(apiKeyVar, crmTokenVar string) CredentialProvider {
+	if apiKeyVar == "" {
+		apiKeyVar = "SMARTIS_API_KEY"
+	}
+
+	if crmTokenVar == "" {
+		crmTokenVar = "SMARTIS_CRM_TOKEN"
+	}
+
+	return CredentialProviderFunc(func(context.Context) (Credentials, error) {
+		return Credentials{
+			APIKey:   Secret(os.Getenv(apiKeyVar)),
+			CRMToken: Secret(os.Getenv(crmTokenVar)),
+		}, nil
+	})
+}
+
+// ChainCredentials takes every field from the first provider that sets it.
+func ChainCredentials(providers ...CredentialProvider) CredentialProvider {
+	return CredentialProviderFunc(func(ctx context.Context) (Credentials, error) {
+		var creds Credentials
+
+		for _, p := range providers {
+			next, err := p.Credentials(ctx)
+			if err != nil {
+				return Credentials{}, err
+			}
+
+			creds = creds.merge(next)
+		}
+
+		return creds, nil
+	})
+}
+
+// FileCredentials reads a JSON file with "api_key" and "crm_token", the
+// format of the smartis CLI config. The file is read again when its
+// modification time changes.
+type FileCredentials struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	creds   Credentials
+}
+
+func NewFileCredentials(path string) *FileCredentials {
+	return &FileCredentials{path: path}
+}
+
+func (f *FileCredentials) Credentials(context.Context) (Credentials, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("credentials file: %w", err)
+	}
+
+	if info.ModTime().Equal(f.modTime) {
+		return f.creds, nil
+	}
+
+	data, err := os.ReadFile(f.path)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("credentials file: %w", err)
+	}
+
+	var raw struct {
+		APIKey   string `json:"api_key"`
+		CRMToken string `json:"crm_token"`
+	}
+
+	err = json.Unmarshal(data, &raw)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("credentials file %s: %w", f.path, err)
+	}
+
+	f.creds = Credentials{APIKey: Secret(raw.APIKey), CRMToken: Secret(raw.CRMToken)}
+	f.modTime = info.ModTime()
+
+	return f.creds, nil
+}
+
+// LocalVault is an in-memory secret store with versioned paths, standing in
+// for a vault service in tests and local setups. It is safe for concurrent
+// use.
+type LocalVault struct {
+	mu      sync.RWMutex
+	secrets map[string][]Credentials
+}
+
+func NewLocalVault() *LocalVault {
+	return &LocalVault{secrets: make(map[string][]Credentials)}
+}
+
+// Put stores a new version of the credentials at path and returns its
+// version number, starting at 1.
+func (v *LocalVault) Put(path string, creds Credentials) int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.secrets[path] = append(v.secrets[path], creds)
+
+	return len(v.secrets[path])
+}
+
+// Get returns the given version of path; version 0 is the latest.
+func (v *LocalVault) Get(path string, version int) (Credentials, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	versions := v.secrets[path]
+	if version == 0 {
+		version = len(versions)
+	}
+
+	if version < 1 || version > len(versions) {
+		return Credentials{}, fmt.Errorf("vault %s version %d: %w", path, version, ErrSecretNotFound)
+	}
+
+	return versions[version-1], nil
+}
+
+// Provider returns a CredentialProvider serving the latest version of path.
+func (v *LocalVault) Provider(path string) CredentialProvider {
+	return CredentialProviderFunc(func(context.Context) (Credentials, error) {
+		return v.Get(path, 0)
+	})
+}
+
+// credentials resolves the credentials of a request: the context first,
+// then Client.Credentials, then APIKey and CRMToken.
+func (c *Client) credentials(ctx context.Context) (Credentials, error) {
+	creds, _ := CredentialsFromContext(ctx)
+
+	if c.Credentials != nil && (creds.APIKey == "" || creds.CRMToken == "") {
+		provided, err := c.Credentials.Credentials(ctx)
+		if err != nil {
+			return Credentials{}, fmt.Errorf("credentials: %w", err)
+		}
+
+		creds = creds.merge(provided)
+	}
+
+	return creds.merge(Credentials{APIKey: Secret(c.APIKey), CRMToken: Secret(c.CRMToken)}), nil
+}
+
+// String keeps credentials out of logs that print the client.
+func (c *Client) String() string {
+	return fmt.Sprintf("gosmartis.Client{Host: %q, APIKey: %s, CRMToken: %s}",
+		c.HOST, Secret(c.APIKey), Secret(c.CRMToken))
+}
+
+func (c *Client) GoString() string {
+	return c.String()
+}
+
+// redactedError hides credentials that an error message may echo.
+type redactedError struct {
+	err error
+	msg string
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// redactError replaces the credentials in the message of err.
+func redactError(err error, creds Credentials) error {
+	if err == nil {
+		return nil
+	}
+
+	msg := err.Error()
+	replaced := msg
+
+	for _, secret := range []Secret{creds.APIKey, creds.CRMToken} {
+		if len(secret) >= 4 {
+			replaced = strings.ReplaceAll(replaced, string(secret), redacted)
+		}
+	}
+
+	if replaced == msg {
+		return err
+	}
+
+	// An API error echoing a credential is replaced rather than wrapped, so
+	// that errors.As does not hand out the original message.
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return &APIError{Msg: replaced}
+	}
+
+	return &redactedError{err: err, msg: replaced}
+}
@@ -0,0 +1,336 @@
+package gosmartis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSecretRedaction(t *testing.T) {
+	creds := Credentials{APIKey: "key-123456", CRMToken: "token-abcdef"}
+
+	outputs := []string{
+		fmt.Sprint(creds),
+		fmt.Sprintf("%v %s %+v %#v", creds, creds.APIKey, creds, creds),
+	}
+
+	data, err := json.Marshal(creds)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outputs = append(outputs, string(data))
+
+	var buf bytes.Buffer
+
+	slog.New(slog.NewJSONHandler(&buf, nil)).Info("call", "creds", creds, "key", creds.APIKey)
+	outputs = append(outputs, buf.String())
+
+	client := NewClient("key-123456", "token-abcdef", nil)
+	outputs = append(outputs, fmt.Sprint(client), fmt.Sprintf("%#v", client))
+
+	for _, out := range outputs {
+		if strings.Contains(out, "123456") || strings.Contains(out, "abcdef") {
+			t.Errorf("secret leaked: %s", out)
+		}
+
+		if !strings.Contains(out, redacted) {
+			t.Errorf("no %s in %s", redacted, out)
+		}
+	}
+
+	if creds.APIKey.Reveal() != "key-123456" {
+		t.Errorf("Reveal = %q", creds.APIKey.Reveal())
+	}
+
+	if Secret("").String() != "" {
+		t.Error("empty secret prints as redacted")
+	}
+}
+
+func TestClientCredentialsPrecedence(t *testing.T) {
+	providerCalls := 0
+	provider := CredentialProviderFunc(func(context.Context) (Credentials, error) {
+		providerCalls++
+
+		return Credentials{APIKey: "provider-key"}, nil
+	})
+
+	tests := []struct {
+		name     string
+		provider CredentialProvider
+		ctx      *Credentials
+		want     Credentials
+		calls    int
+	}{
+		{
+			name: "fields",
+			want: Credentials{APIKey: "field-key", CRMToken: "field-token"},
+		},
+		{
+			name:     "provider over fields",
+			provider: provider,
+			want:     Credentials{APIKey: "provider-key", CRMToken: "field-token"},
+			calls:    1,
+		},
+		{
+			name:     "context over provider",
+			provider: provider,
+			ctx:      &Credentials{CRMToken: "ctx-token"},
+			want:     Credentials{APIKey: "provider-key", CRMToken: "ctx-token"},
+			calls:    1,
+		},
+		{
+			name:     "full context skips provider",
+			provider: provider,
+			ctx:      &Credentials{APIKey: "ctx-key", CRMToken: "ctx-token"},
+			want:     Credentials{APIKey: "ctx-key", CRMToken: "ctx-token"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			providerCalls = 0
+
+			client := NewClient("field-key", "field-token", nil)
+			client.Credentials = tt.provider
+
+			ctx := context.Background()
+			if tt.ctx != nil {
+				ctx = WithCredentials(ctx, *tt.ctx)
+			}
+
+			got, err := client.credentials(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got != tt.want || providerCalls != tt.calls {
+				t.Errorf("got %s/%s with %d provider calls, want %s/%s with %d",
+					got.APIKey.Reveal(), got.CRMToken.Reveal(), providerCalls, tt.want.APIKey.Reveal(), tt.want.CRMToken.Reveal(), tt.calls)
+			}
+		})
+	}
+}
+
+func TestClientCredentialsProviderError(t *testing.T) {
+	want := errors.New("vault sealed")
+	client := NewClient("", "", nil)
+	client.Credentials = CredentialProviderFunc(func(context.Context) (Credentials, error) { return Credentials{}, want })
+
+	_, err := client.credentials(context.Background())
+	if !errors.Is(err, want) {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestWithCredentialsMerges(t *testing.T) {
+	ctx := WithCredentials(context.Background(), Credentials{APIKey: "outer-key", CRMToken: "outer-token"})
+	ctx = WithCredentials(ctx, Credentials{APIKey: "inner-key"})
+
+	creds, ok := CredentialsFromContext(ctx)
+	if !ok || creds.APIKey != "inner-key" || creds.CRMToken != "outer-token" {
+		t.Errorf("creds = %s/%s, %v", creds.APIKey.Reveal(), creds.CRMToken.Reveal(), ok)
+	}
+
+	if _, ok := CredentialsFromContext(context.Background()); ok {
+		t.Error("credentials in an empty context")
+	}
+}
+
+func TestCredentialProviders(t *testing.T) {
+	ctx := context.Background()
+
+	t.Setenv("TEST_SMARTIS_KEY", "env-key")
+	t.Setenv("SMARTIS_CRM_TOKEN", "env-token")
+
+	creds, err := EnvCredentials("TEST_SMARTIS_KEY", "").Credentials(ctx)
+	if err != nil || creds.APIKey != "env-key" || creds.CRMToken != "env-token" {
+		t.Errorf("env = %s/%s, %v", creds.APIKey.Reveal(), creds.CRMToken.Reveal(), err)
+	}
+
+	chain := ChainCredentials(
+		StaticCredentials("", "static-token"),
+		EnvCredentials("TEST_SMARTIS_KEY", "TEST_SMARTIS_UNSET"),
+		StaticCredentials("fallback-key", "fallback-token"),
+	)
+
+	creds, err = chain.Credentials(ctx)
+	if err != nil || creds.APIKey != "env-key" || creds.CRMToken != "static-token" {
+		t.Errorf("chain = %s/%s, %v", creds.APIKey.Reveal(), creds.CRMToken.Reveal(), err)
+	}
+
+	failing := ChainCredentials(StaticCredentials("k", "t"), CredentialProviderFunc(func(context.Context) (Credentials, error) {
+		return Credentials{}, ErrSecretNotFound
+	}))
+
+	_, err = failing.Credentials(ctx)
+	if !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("chain error = %v", err)
+	}
+}
+
+func TestFileCredentialsReload(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "config.json")
+	provider := NewFileCredentials(path)
+
+	_, err := provider.Credentials(ctx)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("missing file: %v", err)
+	}
+
+	write := func(content string, modTime time.Time) {
+		t.Helper()
+
+		err := os.WriteFile(path, []byte(content), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = os.Chtimes(path, modTime, modTime)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	start := time.Now().Add(-time.Hour)
+
+	write(`{"api_key": "key-1", "crm_token": "token-1"}`, start)
+
+	creds, err := provider.Credentials(ctx)
+	if err != nil || creds.APIKey != "key-1" || creds.CRMToken != "token-1" {
+		t.Fatalf("first read = %s, %v", creds.APIKey.Reveal(), err)
+	}
+
+	// Same modification time: the cached credentials are served.
+	write(`{"api_key": "key-2"}`, start)
+
+	creds, _ = provider.Credentials(ctx)
+	if creds.APIKey != "key-1" {
+		t.Errorf("unchanged mtime = %s, want the cached key-1", creds.APIKey.Reveal())
+	}
+
+	write(`{"api_key": "key-2"}`, start.Add(time.Minute))
+
+	creds, _ = provider.Credentials(ctx)
+	if creds.APIKey != "key-2" || creds.CRMToken != "" {
+		t.Errorf("rotated = %s/%s, want key-2 without token", creds.APIKey.Reveal(), creds.CRMToken.Reveal())
+	}
+
+	write(`{`, start.Add(2*time.Minute))
+
+	_, err = provider.Credentials(ctx)
+	if err == nil {
+		t.Error("invalid file is accepted")
+	}
+}
+
+func TestLocalVault(t *testing.T) {
+	vault := NewLocalVault()
+	provider := vault.Provider("smartis/prod")
+
+	_, err := provider.Credentials(context.Background())
+	if !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("empty path: %v", err)
+	}
+
+	if v := vault.Put("smartis/prod", Credentials{APIKey: "key-1"}); v != 1 {
+		t.Errorf("first version = %d", v)
+	}
+
+	if v := vault.Put("smartis/prod", Credentials{APIKey: "key-2"}); v != 2 {
+		t.Errorf("second version = %d", v)
+	}
+
+	creds, err := provider.Credentials(context.Background())
+	if err != nil || creds.APIKey != "key-2" {
+		t.Errorf("latest = %s, %v", creds.APIKey.Reveal(), err)
+	}
+
+	creds, err = vault.Get("smartis/prod", 1)
+	if err != nil || creds.APIKey != "key-1" {
+		t.Errorf("version 1 = %s, %v", creds.APIKey.Reveal(), err)
+	}
+
+	for _, version := range []int{-1, 3} {
+		_, err = vault.Get("smartis/prod", version)
+		if !errors.Is(err, ErrSecretNotFound) {
+			t.Errorf("version %d: %v", version, err)
+		}
+	}
+}
+
+func TestRedactError(t *testing.T) {
+	creds := Credentials{APIKey: "key-123456", CRMToken: "abc"}
+	base := errors.New("dial failed for key-123456 with abc")
+
+	err := redactError(base, creds)
+	if err.Error() != "dial failed for REDACTED with abc" {
+		t.Errorf("message = %q", err.Error())
+	}
+
+	if !errors.Is(err, base) {
+		t.Error("redacted error does not unwrap")
+	}
+
+	apiErr := redactError(&APIError{Msg: "invalid token key-123456"}, creds)
+
+	var target *APIError
+	if !errors.As(apiErr, &target) || target.Msg != "invalid token REDACTED" {
+		t.Errorf("API error = %v", apiErr)
+	}
+
+	if clean := errors.New("timeout"); redactError(clean, creds) != clean {
+		t.Error("an error without secrets is replaced")
+	}
+
+	if redactError(nil, creds) != nil {
+		t.Error("nil error is not nil")
+	}
+}
+
+func TestPerCallCRMToken(t *testing.T) {
+	api := &fakeAPI{handle: func(string, map[string]interface{}) (int, string) {
+		return http.StatusOK, `{"crmCustomFields": [{"id": 1, "title": "Source"}]}`
+	}}
+	client := api.client()
+	client.CRMToken = ""
+
+	_, err := client.GetCRMCustomFields(context.Background(), []int{1})
+	if !errors.Is(err, ErrEmptyCRMToken) {
+		t.Errorf("without token: %v", err)
+	}
+
+	ctx := WithCredentials(context.Background(), Credentials{APIKey: "tenant-key", CRMToken: "tenant-token"})
+
+	fields, err := client.GetCRMCustomFields(ctx, []int{1})
+	if err != nil || len(fields) != 1 {
+		t.Fatalf("GetCRMCustomFields = %v, %v", fields, err)
+	}
+
+	req := api.requests[len(api.requests)-1]
+	if req.body["smartis_crm_token"] != "tenant-token" || req.header.Get("Authorization") != "Bearer tenant-key" {
+		t.Errorf("request used token %v and %q", req.body["smartis_crm_token"], req.header.Get("Authorization"))
+	}
+}
+
+func TestTransportErrorRedacted(t *testing.T) {
+	client := NewClient("key-123456", "", &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, fmt.Errorf("proxy rejected %s", req.Header.Get("Authorization"))
+	})})
+
+	_, err := client.GetProjects(context.Background())
+	if err == nil || strings.Contains(err.Error(), "123456") || !strings.Contains(err.Error(), redacted) {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -0,0 +1,323 @@
+package gosmartis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CRMFieldType is the kind of value a CRM custom field holds. Smartis keeps
+// the numbering of the amoCRM field types.
+type CRMFieldType int
+
+const (
+	CRMFieldText          CRMFieldType = 1
+	CRMFieldNumeric       CRMFieldType = 2
+	CRMFieldCheckbox      CRMFieldType = 3
+	CRMFieldSelect        CRMFieldType = 4
+	CRMFieldMultiSelect   CRMFieldType = 5
+	CRMFieldDate          CRMFieldType = 6
+	CRMFieldURL           CRMFieldType = 7
+	CRMFieldMultiText     CRMFieldType = 8
+	CRMFieldTextArea      CRMFieldType = 9
+	CRMFieldRadioButton   CRMFieldType = 10
+	CRMFieldStreetAddress CRMFieldType = 11
+	CRMFieldSmartAddress  CRMFieldType = 13
+	CRMFieldBirthday      CRMFieldType = 14
+	CRMFieldLegalEntity   CRMFieldType = 15
+	CRMFieldItems         CRMFieldType = 16
+	CRMFieldOrgLegalName  CRMFieldType = 17
+	CRMFieldDateTime      CRMFieldType = 19
+)
+
+var crmFieldTypeNames = map[CRMFieldType]string{
+	CRMFieldText:          "text",
+	CRMFieldNumeric:       "numeric",
+	CRMFieldCheckbox:      "checkbox",
+	CRMFieldSelect:        "select",
+	CRMFieldMultiSelect:   "multiselect",
+	CRMFieldDate:          "date",
+	CRMFieldURL:           "url",
+	CRMFieldMultiText:     "multitext",
+	CRMFieldTextArea:      "textarea",
+	CRMFieldRadioButton:   "radiobutton",
+	CRMFieldStreetAddress: "street_address",
+	CRMFieldSmartAddress:  "smart_address",
+	CRMFieldBirthday:      "birthday",
+	CRMFieldLegalEntity:   "legal_entity",
+	CRMFieldItems:         "items",
+	CRMFieldOrgLegalName:  "org_legal_name",
+	CRMFieldDateTime:      "date_time",
+}
+
+func (t CRMFieldType) String() string {
+	if name, ok := crmFieldTypeNames[t]; ok {
+		return name
+	}
+
+	return "field_type_" + strconv.Itoa(int(t))
+}
+
+func (t CRMFieldType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// CRMElementType is the CRM entity a custom field belongs to.
+type CRMElementType int
+
+const (
+	CRMElementContact  CRMElementType = 1
+	CRMElementLead     CRMElementType = 2
+	CRMElementCompany  CRMElementType = 3
+	CRMElementCustomer CRMElementType = 12
+)
+
+var crmElementTypeNames = map[CRMElementType]string{
+	CRMElementContact:  "contact",
+	CRMElementLead:     "lead",
+	CRMElementCompany:  "company",
+	CRMElementCustomer: "customer",
+}
+
+func (t CRMElementType) String() string {
+	if name, ok := crmElementTypeNames[t]; ok {
+		return name
+	}
+
+	return "element_type_" + strconv.Itoa(int(t))
+}
+
+func (t CRMElementType) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+// CRMField is a custom field of the CRMSchema.
+type CRMField struct {
+	ID          int            `json:"id"`
+	Title       string         `json:"title"`
+	Description string         `json:"description,omitempty"`
+	Type        CRMFieldType   `json:"type"`
+	Element     CRMElementType `json:"element"`
+	GroupID     int            `json:"group_id,omitempty"`
+	Multiple    bool           `json:"multiple"`
+	Filterable  bool           `json:"filterable"`
+	// FilterParamID is the Filter name of filterable fields.
+	FilterParamID  int  `json:"filter_param_id,omitempty"`
+	Status         int  `json:"status"`
+	DefaultVisible bool `json:"default_visible"`
+}
+
+// Column returns the report column ID of the field.
+func (f CRMField) Column() string {
+	return "field_" + strconv.Itoa(f.ID)
+}
+
+// Filter builds a report filter on the field. It fails for fields that are
+// not filterable.
+func (f CRMField) Filter(operator, value string) (Filter, error) {
+	if !f.Filterable || f.FilterParamID == 0 {
+		return Filter{}, fmt.Errorf("crm field %d %q is not filterable", f.ID, f.Title)
+	}
+
+	return Filter{Name: strconv.Itoa(f.FilterParamID), Operator: operator, Value: value}, nil
+}
+
+// CRMGroup is a custom field group with its fields.
+type CRMGroup struct {
+	ID             int        `json:"id"`
+	Title          string     `json:"title"`
+	Sort           int        `json:"sort"`
+	DefaultVisible bool       `json:"default_visible"`
+	Fields         []CRMField `json:"fields"`
+}
+
+// CRMSchema is the catalogue of CRM custom fields nested under their
+// groups. Fields of unknown groups are listed in Ungrouped.
+type CRMSchema struct {
+	Groups    []CRMGroup `json:"groups"`
+	Ungrouped []CRMField `json:"ungrouped,omitempty"`
+}
+
+// LoadCRMSchema fetches all custom fields and groups. Like the crm-fields
+// command, it asks for all of them with an empty ID list, not with null.
+func LoadCRMSchema(ctx context.Context, api API) (*CRMSchema, error) {
+	fields, err := api.GetCRMCustomFields(ctx, []int{})
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := api.GetCRMCustomFieldGroups(ctx, []int{})
+	if err != nil {
+		return nil, err
+	}
+
+	return NewCRMSchema(fields, groups), nil
+}
+
+// NewCRMSchema nests fields under groups. Groups are ordered by Sort and
+// fields by title.
+func NewCRMSchema(fields []CrmCustomField, groups []CrmCustomFieldGroup) *CRMSchema {
+	schema := &CRMSchema{Groups: make([]CRMGroup, 0, len(groups))}
+	index := make(map[int]int, len(groups))
+
+	for _, g := range groups {
+		index[g.ID] = len(schema.Groups)
+		schema.Groups = append(schema.Groups, CRMGroup{
+			ID:             g.ID,
+			Title:          g.Title,
+			Sort:           g.Sort,
+			DefaultVisible: bool(g.DefaultVisibility),
+			Fields:         []CRMField{},
+		})
+	}
+
+	for _, f := range fields {
+		field := CRMField{
+			ID:             f.ID,
+			Title:          f.CustomFieldTitle,
+			Description:    f.Description,
+			Type:           CRMFieldType(f.FieldTypeID),
+			Element:        CRMElementType(f.ElementTypeID),
+			GroupID:        f.GroupID,
+			Multiple:       bool(f.IsMultiple),
+			Filterable:     bool(f.IsFilter),
+			FilterParamID:  f.FilterParamID,
+			Status:         f.Status,
+			DefaultVisible: bool(f.DefaultVisibility),
+		}
+
+		if i, ok := index[f.GroupID]; ok {
+			schema.Groups[i].Fields = append(schema.Groups[i].Fields, field)
+		} else {
+			schema.Ungrouped = append(schema.Ungrouped, field)
+		}
+	}
+
+	sort.SliceStable(schema.Groups, func(i, j int) bool {
+		if schema.Groups[i].Sort != schema.Groups[j].Sort {
+			return schema.Groups[i].Sort < schema.Groups[j].Sort
+		}
+
+		return schema.Groups[i].ID < schema.Groups[j].ID
+	})
+
+	for i := range schema.Groups {
+		sortCRMFields(schema.Groups[i].Fields)
+	}
+
+	sortCRMFields(schema.Ungrouped)
+
+	return schema
+}
+
+func sortCRMFields(fields []CRMField) {
+	sort.Slice(fields, func(i, j int) bool {
+		if fields[i].Title != fields[j].Title {
+			return fields[i].Title < fields[j].Title
+		}
+
+		return fields[i].ID < fields[j].ID
+	})
+}
+
+// Fields returns all fields in schema order.
+func (s *CRMSchema) Fields() []CRMField {
+	var fields []CRMField
+
+	for _, g := range s.Groups {
+		fields = append(fields, g.Fields...)
+	}
+
+	return append(fields, s.Ungrouped...)
+}
+
+// Field returns the field with the given ID.
+func (s *CRMSchema) Field(id int) (CRMField, bool) {
+	for _, f := range s.Fields() {
+		if f.ID == id {
+			return f, true
+		}
+	}
+
+	return CRMField{}, false
+}
+
+// Filterable returns the fields usable in report filters.
+func (s *CRMSchema) Filterable() []CRMField {
+	var fields []CRMField
+
+	for _, f := range s.Fields() {
+		if f.Filterable && f.FilterParamID != 0 {
+			fields = append(fields, f)
+		}
+	}
+
+	return fields
+}
+
+// WriteJSON writes the schema as an indented JSON document.
+func (s *CRMSchema) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(s)
+}
+
+// WriteMarkdown writes the schema as a Markdown document with a table of
+// fields per group.
+func (s *CRMSchema) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+
+	b.WriteString("# CRM custom fields\n")
+
+	for _, g := range s.Groups {
+		fmt.Fprintf(&b, "\n## %s (group %d)\n\n", markdownEscape(g.Title), g.ID)
+		writeCRMFieldsTable(&b, g.Fields)
+	}
+
+	if len(s.Ungrouped) > 0 {
+		b.WriteString("\n## Ungrouped\n\n")
+		writeCRMFieldsTable(&b, s.Ungrouped)
+	}
+
+	_, err := io.WriteString(w, b.String())
+
+	return err
+}
+
+func writeCRMFieldsTable(b *strings.Builder, fields []CRMField) {
+	if len(fields) == 0 {
+		b.WriteString("No fields.\n")
+
+		return
+	}
+
+	b.WriteString("| ID | Column | Title | Type | Element | Multiple | Filter |\n")
+	b.WriteString("|---:|---|---|---|---|---|---|\n")
+
+	for _, f := range fields {
+		filter := ""
+		if f.Filterable && f.FilterParamID != 0 {
+			filter = strconv.Itoa(f.FilterParamID)
+		}
+
+		fmt.Fprintf(b, "| %d | `%s` | %s | %s | %s | %s | %s |\n",
+			f.ID, f.Column(), markdownEscape(f.Title), f.Type, f.Element, yesNo(f.Multiple), filter)
+	}
+}
+
+func markdownEscape(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+
+	return "no"
+}
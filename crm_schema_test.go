@@ -0,0 +1,192 @@
+package gosmartis_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zfullio/gosmartis"
+	"github.com/zfullio/gosmartis/mock"
+)
+
+func testCRMSchema() *gosmartis.CRMSchema {
+	fields := []gosmartis.CrmCustomField{
+		{ID: 5, CustomFieldTitle: "Source", FieldTypeID: 4, ElementTypeID: 2, GroupID: 2, IsFilter: true, FilterParamID: 31},
+		{ID: 4, CustomFieldTitle: "Budget", FieldTypeID: 2, ElementTypeID: 2, GroupID: 2, IsMultiple: true},
+		{ID: 8, CustomFieldTitle: "Budget", FieldTypeID: 2, ElementTypeID: 3, GroupID: 2, IsFilter: true},
+		{ID: 6, CustomFieldTitle: "Phone | work", Description: "Work phone", FieldTypeID: 8, ElementTypeID: 1, GroupID: 1},
+		{ID: 7, CustomFieldTitle: "Legacy\nfield", FieldTypeID: 99, ElementTypeID: 42, GroupID: 9, Status: 1},
+	}
+
+	groups := []gosmartis.CrmCustomFieldGroup{
+		{ID: 2, Title: "Deal | terms", Sort: 20},
+		{ID: 3, Title: "Empty", Sort: 10},
+		{ID: 1, Title: "Contact", Sort: 10, DefaultVisibility: true},
+	}
+
+	return gosmartis.NewCRMSchema(fields, groups)
+}
+
+func TestNewCRMSchema(t *testing.T) {
+	schema := testCRMSchema()
+
+	var got []string
+
+	for _, g := range schema.Groups {
+		got = append(got, g.Title)
+
+		for _, f := range g.Fields {
+			if f.GroupID != g.ID {
+				t.Errorf("field %d of group %d is listed under group %d", f.ID, f.GroupID, g.ID)
+			}
+
+			got = append(got, "  "+f.Column())
+		}
+	}
+
+	want := []string{"Contact", "  field_6", "Empty", "Deal | terms", "  field_4", "  field_8", "  field_5"}
+	if len(got) != len(want) {
+		t.Fatalf("groups and fields = %q, want %q", got, want)
+	}
+
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("groups and fields = %q, want %q", got, want)
+		}
+	}
+
+	// Group 9 is missing, so its field is listed as ungrouped.
+	if len(schema.Ungrouped) != 1 || schema.Ungrouped[0].ID != 7 {
+		t.Errorf("ungrouped = %+v", schema.Ungrouped)
+	}
+
+	if schema.Groups[1].Fields == nil {
+		t.Error("an empty group has nil fields")
+	}
+
+	if n := len(schema.Fields()); n != 5 {
+		t.Errorf("Fields returned %d fields", n)
+	}
+
+	if f, ok := schema.Field(7); !ok || f.Title != "Legacy\nfield" {
+		t.Errorf("Field(7) = %+v, %v", f, ok)
+	}
+
+	if _, ok := schema.Field(100); ok {
+		t.Error("Field(100) found a field")
+	}
+
+	// Field 8 has no filter param, so it cannot be used in a filter.
+	filterable := schema.Filterable()
+	if len(filterable) != 1 || filterable[0].ID != 5 {
+		t.Errorf("filterable = %+v", filterable)
+	}
+}
+
+func TestCRMFieldTypeNames(t *testing.T) {
+	tests := []struct {
+		got, want string
+	}{
+		{gosmartis.CRMFieldSelect.String(), "select"},
+		{gosmartis.CRMFieldDateTime.String(), "date_time"},
+		{gosmartis.CRMFieldType(99).String(), "field_type_99"},
+		{gosmartis.CRMElementLead.String(), "lead"},
+		{gosmartis.CRMElementType(42).String(), "element_type_42"},
+	}
+
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("got %q, want %q", tt.got, tt.want)
+		}
+	}
+}
+
+func TestCRMFieldFilter(t *testing.T) {
+	schema := testCRMSchema()
+
+	source, _ := schema.Field(5)
+
+	filter, err := source.Filter("=", "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if filter.Name != "31" || filter.Operator != "=" || filter.Value != "web" {
+		t.Errorf("filter = %+v", filter)
+	}
+
+	for _, id := range []int{6, 8} {
+		field, _ := schema.Field(id)
+
+		_, err = field.Filter("=", "x")
+		if err == nil {
+			t.Errorf("field %d is filterable", id)
+		}
+	}
+}
+
+func TestCRMSchemaOutput(t *testing.T) {
+	schema := testCRMSchema()
+
+	tests := []struct {
+		golden string
+		write  func(io.Writer) error
+	}{
+		{"crm_schema.json", schema.WriteJSON},
+		{"crm_schema.md", schema.WriteMarkdown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.golden, func(t *testing.T) {
+			want, err := os.ReadFile(filepath.Join("testdata", tt.golden))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var out bytes.Buffer
+
+			err = tt.write(&out)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if out.String() != string(want) {
+				t.Errorf("output:\n%s\nwant:\n%s", out.String(), want)
+			}
+		})
+	}
+}
+
+func TestLoadCRMSchema(t *testing.T) {
+	var fieldIDs, groupIDs []int
+
+	api := &mock.APIMock{
+		GetCRMCustomFieldsFunc: func(_ context.Context, ids []int) ([]gosmartis.CrmCustomField, error) {
+			fieldIDs = ids
+
+			return []gosmartis.CrmCustomField{{ID: 1, CustomFieldTitle: "Source", GroupID: 1}}, nil
+		},
+		GetCRMCustomFieldGroupsFunc: func(_ context.Context, ids []int) ([]gosmartis.CrmCustomFieldGroup, error) {
+			groupIDs = ids
+
+			return []gosmartis.CrmCustomFieldGroup{{ID: 1, Title: "Deal"}}, nil
+		},
+	}
+
+	schema, err := gosmartis.LoadCRMSchema(context.Background(), api)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An empty list asks for all fields; null is not documented to.
+	if fieldIDs == nil || len(fieldIDs) != 0 || groupIDs == nil || len(groupIDs) != 0 {
+		t.Errorf("requested fields %#v and groups %#v, want empty lists", fieldIDs, groupIDs)
+	}
+
+	if len(schema.Groups) != 1 || len(schema.Groups[0].Fields) != 1 {
+		t.Errorf("schema = %+v", schema)
+	}
+}
@@ -0,0 +1,501 @@
+package gosmartis
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type DiffKind string
+
+const (
+	DiffAdded   DiffKind = "added"
+	DiffRemoved DiffKind = "removed"
+	DiffChanged DiffKind = "changed"
+)
+
+// DefaultAbsTolerance is the absolute difference under which two numbers
+// are considered equal when DiffOptions.AbsTolerance is zero.
+const DefaultAbsTolerance = 1e-9
+
+type DiffOptions struct {
+	// KeyColumns match rows between the reports. By default the dimension
+	// columns inferred by InferColumns are used.
+	KeyColumns []string
+	// AbsTolerance treats numbers closer than this as equal.
+	AbsTolerance float64
+	// RelTolerance treats numbers whose difference relative to the larger
+	// of them is below this as equal.
+	RelTolerance float64
+}
+
+// CellDelta is the change of one column of a row.
+type CellDelta struct {
+	Column string      `json:"column"`
+	Old    interface{} `json:"old"`
+	New    interface{} `json:"new"`
+	// Abs is New minus Old for numeric columns.
+	Abs float64 `json:"abs"`
+	// Rel is Abs relative to Old; nil when Old is zero or not numeric.
+	Rel *float64 `json:"rel"`
+}
+
+type RowDiff struct {
+	Kind   DiffKind               `json:"kind"`
+	Key    map[string]interface{} `json:"key"`
+	Old    Row                    `json:"-"`
+	New    Row                    `json:"-"`
+	Deltas []CellDelta            `json:"deltas,omitempty"`
+}
+
+// ColumnTotal compares the sums of a numeric column.
+type ColumnTotal struct {
+	Column string   `json:"column"`
+	Old    float64  `json:"old"`
+	New    float64  `json:"new"`
+	Abs    float64  `json:"abs"`
+	Rel    *float64 `json:"rel"`
+}
+
+type ReportDiff struct {
+	Metric     string   `json:"metric"`
+	KeyColumns []string `json:"key_columns"`
+	Added      int      `json:"added"`
+	Removed    int      `json:"removed"`
+	Changed    int      `json:"changed"`
+	Unchanged  int      `json:"unchanged"`
+	// Duplicates counts rows that shared a key with an earlier row of the
+	// same report and were summed into it.
+	Duplicates int           `json:"duplicates"`
+	Rows       []RowDiff     `json:"rows"`
+	Totals     []ColumnTotal `json:"totals"`
+}
+
+// Empty reports whether the reports hold the same rows.
+func (d *ReportDiff) Empty() bool {
+	return d.Added == 0 && d.Removed == 0 && d.Changed == 0
+}
+
+// Diff matches the rows of a and b by keyColumns and reports added, removed
+// and changed rows. Either report may be nil. Rows of one report that share
+// a key are merged before matching by summing their numeric non-dimension
+// columns, so a report split finer than keyColumns is compared by its
+// totals.
+func Diff(a, b *Report, keyColumns ...string) *ReportDiff {
+	return DiffWithOptions(a, b, DiffOptions{KeyColumns: keyColumns})
+}
+
+// DiffWithOptions is Diff with configurable keys and float tolerances.
+func DiffWithOptions(a, b *Report, opts DiffOptions) *ReportDiff {
+	var oldRows, newRows []Row
+
+	result := &ReportDiff{}
+
+	if a != nil {
+		oldRows = a.RowsMassive
+		result.Metric = a.Metric
+	}
+
+	if b != nil {
+		newRows = b.RowsMassive
+		if result.Metric == "" {
+			result.Metric = b.Metric
+		}
+	}
+
+	if opts.AbsTolerance == 0 {
+		opts.AbsTolerance = DefaultAbsTolerance
+	}
+
+	columns := InferColumns(append(append(make([]Row, 0, len(oldRows)+len(newRows)), oldRows...), newRows...))
+
+	keys := opts.KeyColumns
+	if len(keys) == 0 {
+		for _, col := range columns {
+			if col.Dimension {
+				keys = append(keys, col.ID)
+			}
+		}
+	}
+
+	isKey := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		isKey[key] = true
+	}
+
+	// measures are the numeric columns summed when rows share a key;
+	// numeric identifiers such as ad_id are not.
+	var values, numeric, measures []string
+
+	for _, col := range columns {
+		if isKey[col.ID] {
+			continue
+		}
+
+		values = append(values, col.ID)
+		if col.Kind == ColumnInt || col.Kind == ColumnFloat {
+			numeric = append(numeric, col.ID)
+			if !col.Dimension {
+				measures = append(measures, col.ID)
+			}
+		}
+	}
+
+	result.KeyColumns = keys
+
+	oldIndex, oldDuplicates := indexRows(oldRows, keys, measures)
+	newIndex, newDuplicates := indexRows(newRows, keys, measures)
+	result.Duplicates = oldDuplicates + newDuplicates
+
+	for _, key := range unionKeys(oldIndex, newIndex) {
+		oldRow, inOld := oldIndex[key]
+		newRow, inNew := newIndex[key]
+
+		row := RowDiff{Old: oldRow, New: newRow}
+
+		switch {
+		case !inOld:
+			row.Kind = DiffAdded
+			row.Key = rowKey(newRow, keys)
+			result.Added++
+		case !inNew:
+			row.Kind = DiffRemoved
+			row.Key = rowKey(oldRow, keys)
+			result.Removed++
+		default:
+			row.Key = rowKey(newRow, keys)
+		}
+
+		for _, col := range values {
+			delta, changed := cellDelta(col, oldRow.Value(col), newRow.Value(col), opts)
+			if changed || row.Kind != "" {
+				row.Deltas = append(row.Deltas, delta)
+			}
+		}
+
+		if row.Kind == "" {
+			if len(row.Deltas) == 0 {
+				result.Unchanged++
+
+				continue
+			}
+
+			row.Kind = DiffChanged
+			result.Changed++
+		}
+
+		result.Rows = append(result.Rows, row)
+	}
+
+	for _, col := range numeric {
+		total := ColumnTotal{Column: col}
+
+		for _, row := range oldRows {
+			v, _ := CellFloat(row.Value(col))
+			total.Old += v
+		}
+
+		for _, row := range newRows {
+			v, _ := CellFloat(row.Value(col))
+			total.New += v
+		}
+
+		total.Abs = total.New - total.Old
+		total.Rel = relative(total.Old, total.Abs)
+		result.Totals = append(result.Totals, total)
+	}
+
+	return result
+}
+
+// Value returns the value of the cell with the given ColumnID, or nil.
+func (r Row) Value(columnID string) interface{} {
+	for _, cell := range r {
+		if cell.ColumnID == columnID {
+			return cell.Value
+		}
+	}
+
+	return nil
+}
+
+func cellDelta(col string, old, new interface{}, opts DiffOptions) (CellDelta, bool) {
+	delta := CellDelta{Column: col, Old: old, New: new}
+
+	oldNum, oldOK := CellFloat(old)
+	newNum, newOK := CellFloat(new)
+
+	if (oldOK || old == nil) && (newOK || new == nil) && (oldOK || newOK) {
+		delta.Abs = newNum - oldNum
+		delta.Rel = relative(oldNum, delta.Abs)
+
+		if old == nil || new == nil {
+			return delta, true
+		}
+
+		return delta, !withinTolerance(oldNum, newNum, opts)
+	}
+
+	return delta, fmt.Sprint(old) != fmt.Sprint(new)
+}
+
+func withinTolerance(a, b float64, opts DiffOptions) bool {
+	diff := math.Abs(a - b)
+	if diff <= opts.AbsTolerance {
+		return true
+	}
+
+	return opts.RelTolerance > 0 && diff <= opts.RelTolerance*math.Max(math.Abs(a), math.Abs(b))
+}
+
+func relative(old, abs float64) *float64 {
+	if old == 0 {
+		return nil
+	}
+
+	rel := abs / math.Abs(old)
+
+	return &rel
+}
+
+// indexRows maps rows by their key. Rows with the same key are merged by
+// summing the measures; the other columns keep the first row's values.
+// The second result is the number of rows merged away.
+func indexRows(rows []Row, keys, measures []string) (map[string]Row, int) {
+	index := make(map[string]Row, len(rows))
+	duplicates := 0
+
+	for _, row := range rows {
+		key := keyString(row, keys)
+
+		prev, ok := index[key]
+		if !ok {
+			index[key] = row
+
+			continue
+		}
+
+		duplicates++
+		index[key] = sumRows(prev, row, measures)
+	}
+
+	return index, duplicates
+}
+
+// sumRows returns a copy of a with the measures of b added to it.
+func sumRows(a, b Row, measures []string) Row {
+	merged := make(Row, 0, len(a))
+	for _, cell := range a {
+		c := *cell
+		merged = append(merged, &c)
+	}
+
+	for _, col := range measures {
+		av, aOK := CellFloat(a.Value(col))
+		bv, bOK := CellFloat(b.Value(col))
+
+		if !aOK && !bOK {
+			continue
+		}
+
+		found := false
+
+		for _, cell := range merged {
+			if cell.ColumnID == col {
+				cell.Value = av + bv
+				found = true
+			}
+		}
+
+		if !found {
+			merged = append(merged, NewCell(col, av+bv, ""))
+		}
+	}
+
+	return merged
+}
+
+// keyString joins the values of the key columns of row into a map key.
+func keyString(row Row, keys []string) string {
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprint(row.Value(key)))
+	}
+
+	return strings.Join(parts, "\x00")
+}
+
+func unionKeys(a, b map[string]Row) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for key := range a {
+		keys = append(keys, key)
+	}
+
+	for key := range b {
+		if _, ok := a[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func rowKey(row Row, keys []string) map[string]interface{} {
+	values := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		values[key] = row.Value(key)
+	}
+
+	return values
+}
+
+// WriteText writes a human-readable summary followed by one line per
+// differing row.
+func (d *ReportDiff) WriteText(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "%s: %d added, %d removed, %d changed, %d unchanged",
+		d.Metric, d.Added, d.Removed, d.Changed, d.Unchanged)
+	if err != nil {
+		return err
+	}
+
+	if d.Duplicates > 0 {
+		_, err = fmt.Fprintf(w, ", %d duplicate rows summed", d.Duplicates)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintln(w)
+	if err != nil {
+		return err
+	}
+
+	for _, total := range d.Totals {
+		_, err = fmt.Fprintf(w, "  total %s: %s -> %s (%s%s)\n",
+			total.Column, formatFloat(total.Old), formatFloat(total.New), signed(total.Abs), formatRel(total.Rel))
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, row := range d.Rows {
+		parts := make([]string, 0, len(row.Deltas))
+
+		for _, delta := range row.Deltas {
+			if _, ok := CellFloat(delta.Old); ok || delta.Old == nil {
+				if _, ok := CellFloat(delta.New); ok || delta.New == nil {
+					parts = append(parts, fmt.Sprintf("%s %v -> %v (%s%s)",
+						delta.Column, valueText(delta.Old), valueText(delta.New), signed(delta.Abs), formatRel(delta.Rel)))
+
+					continue
+				}
+			}
+
+			parts = append(parts, fmt.Sprintf("%s %q -> %q", delta.Column, valueText(delta.Old), valueText(delta.New)))
+		}
+
+		_, err = fmt.Fprintf(w, "%-8s %s: %s\n", row.Kind, d.keyText(row.Key), strings.Join(parts, ", "))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteJSON writes the diff as an indented JSON document.
+func (d *ReportDiff) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(d)
+}
+
+// WriteCSV writes one record per changed cell: kind, key columns, column,
+// old, new, abs and rel.
+func (d *ReportDiff) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	header := append([]string{"kind"}, d.KeyColumns...)
+	header = append(header, "column", "old", "new", "abs", "rel")
+
+	err := writer.Write(header)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range d.Rows {
+		for _, delta := range row.Deltas {
+			record := make([]string, 0, len(header))
+			record = append(record, string(row.Kind))
+
+			for _, key := range d.KeyColumns {
+				record = append(record, valueText(row.Key[key]))
+			}
+
+			rel := ""
+			if delta.Rel != nil {
+				rel = formatFloat(*delta.Rel)
+			}
+
+			record = append(record, delta.Column, valueText(delta.Old), valueText(delta.New), formatFloat(delta.Abs), rel)
+
+			err = writer.Write(record)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+func (d *ReportDiff) keyText(key map[string]interface{}) string {
+	parts := make([]string, 0, len(d.KeyColumns))
+	for _, col := range d.KeyColumns {
+		parts = append(parts, col+"="+valueText(key[col]))
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func valueText(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+
+	if f, ok := value.(float64); ok {
+		return formatFloat(f)
+	}
+
+	return fmt.Sprint(value)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func signed(f float64) string {
+	if f >= 0 {
+		return "+" + formatFloat(f)
+	}
+
+	return formatFloat(f)
+}
+
+func formatRel(rel *float64) string {
+	if rel == nil {
+		return ""
+	}
+
+	return fmt.Sprintf(", %+.2f%%", *rel*100)
+}
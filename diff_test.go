@@ -0,0 +1,102 @@
+package gosmartis
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func adRow(day string, adID, leads float64) Row {
+	return Row{NewCell("day", day, ""), NewCell("ad_id", adID, ""), NewCell("leads", leads, "")}
+}
+
+func TestDiff(t *testing.T) {
+	old := &Report{Metric: "leads", RowsMassive: []Row{
+		adRow("2024-01-01", 1, 3),
+		adRow("2024-01-01", 2, 4),
+		adRow("2024-01-01", 3, 1),
+	}}
+	new := &Report{Metric: "leads", RowsMassive: []Row{
+		adRow("2024-01-01", 1, 3),
+		adRow("2024-01-01", 2, 6),
+		adRow("2024-01-01", 4, 2),
+	}}
+
+	diff := Diff(old, new)
+
+	if diff.Added != 1 || diff.Removed != 1 || diff.Changed != 1 || diff.Unchanged != 1 {
+		t.Fatalf("counts = +%d -%d ~%d =%d", diff.Added, diff.Removed, diff.Changed, diff.Unchanged)
+	}
+
+	if strings.Join(diff.KeyColumns, ",") != "ad_id,day" {
+		t.Errorf("keys = %v", diff.KeyColumns)
+	}
+
+	for _, row := range diff.Rows {
+		if row.Kind == DiffChanged && (row.Key["ad_id"] != 2.0 || row.Deltas[0].Abs != 2) {
+			t.Errorf("changed row = %+v", row)
+		}
+	}
+
+	if len(diff.Totals) != 1 || diff.Totals[0].Old != 8 || diff.Totals[0].New != 11 {
+		t.Errorf("totals = %+v", diff.Totals)
+	}
+}
+
+func TestDiffTolerance(t *testing.T) {
+	old := &Report{RowsMassive: []Row{adRow("2024-01-01", 1, 100)}}
+	new := &Report{RowsMassive: []Row{adRow("2024-01-01", 1, 100.5)}}
+
+	if diff := DiffWithOptions(old, new, DiffOptions{RelTolerance: 0.01}); !diff.Empty() {
+		t.Errorf("within tolerance: %+v", diff.Rows)
+	}
+
+	if diff := Diff(old, new); diff.Changed != 1 {
+		t.Errorf("without tolerance: changed = %d", diff.Changed)
+	}
+}
+
+func TestDiffSumsDuplicateKeys(t *testing.T) {
+	// The API splits a day by a column that is not among the keys.
+	old := &Report{Metric: "leads", RowsMassive: []Row{
+		adRow("2024-01-01", 1, 2),
+		adRow("2024-01-01", 1, 3),
+	}}
+	same := &Report{Metric: "leads", RowsMassive: []Row{
+		adRow("2024-01-01", 1, 5),
+	}}
+
+	diff := Diff(old, same, "day")
+	if !diff.Empty() {
+		t.Errorf("summed duplicates differ: %+v", diff.Rows)
+	}
+
+	if diff.Duplicates != 1 {
+		t.Errorf("duplicates = %d, want 1", diff.Duplicates)
+	}
+
+	moved := &Report{Metric: "leads", RowsMassive: []Row{
+		adRow("2024-01-01", 1, 4),
+		adRow("2024-01-01", 1, 3),
+	}}
+
+	diff = Diff(old, moved, "day")
+	if diff.Changed != 1 || diff.Rows[0].Deltas[0].Old != 5.0 || diff.Rows[0].Deltas[0].New != 7.0 {
+		t.Fatalf("rows = %+v, want leads 5 -> 7", diff.Rows)
+	}
+
+	if old.RowsMassive[0].Value("leads") != 2.0 {
+		t.Error("summing modified the report")
+	}
+
+	var buf bytes.Buffer
+
+	err := diff.WriteText(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(buf.String(), "leads: 0 added, 0 removed, 1 changed, 0 unchanged, 2 duplicate rows summed\n") {
+		t.Errorf("text = %q", buf.String())
+	}
+}
@@ -48,49 +48,69 @@ const (
 )
 
 type Channel struct {
-	ID                  int         `json:"id"`
-	Title               string      `json:"title"`
-	Name                *string     `json:"name"`
-	IsActive            bool        `json:"isActive"`
-	IsVisible           bool        `json:"isVisible"`
-	IsDefaultForChannel bool        `json:"is_default_for_channel"`
-	ParentChannelID     int         `json:"parent_channel_id"`
-	NumLevel            int         `json:"num_level"`
-	CatID               string      `json:"cat_id"`
-	ServiceID           interface{} `json:"service_id"`
-	ClientID            int         `json:"client_id"`
-	GroupingID          int         `json:"grouping_id"`
-	ClassData           interface{} `json:"classData"`
-	GetDataMethod       interface{} `json:"getDataMethod"`
-	DateCreate          string      `json:"date_create"`
-	Sort                int         `json:"sort"`
-	CreatedAt           *string     `json:"created_at"`
-	UpdatedAt           *string     `json:"updated_at"`
-	DeletedAt           interface{} `json:"deleted_at"`
-	CategoryTitle       *string     `json:"category_title"`
+	ID                  int      `json:"id"`
+	Title               string   `json:"title"`
+	Name                *string  `json:"name"`
+	IsActive            FlexBool `json:"isActive"`
+	IsVisible           FlexBool `json:"isVisible"`
+	IsDefaultForChannel FlexBool `json:"is_default_for_channel"`
+	ParentChannelID     FlexInt  `json:"parent_channel_id"`
+	NumLevel            int      `json:"num_level"`
+	CatID               string   `json:"cat_id"`
+	ServiceID           *FlexInt `json:"service_id"`
+	ClientID            int      `json:"client_id"`
+	GroupingID          int      `json:"grouping_id"`
+	ClassData           RawJSON  `json:"classData"`
+	GetDataMethod       RawJSON  `json:"getDataMethod"`
+	DateCreate          FlexTime `json:"date_create"`
+	Sort                int      `json:"sort"`
+	CreatedAt           FlexTime `json:"created_at"`
+	UpdatedAt           FlexTime `json:"updated_at"`
+	DeletedAt           FlexTime `json:"deleted_at"`
+	CategoryTitle       *string  `json:"category_title"`
+}
+
+// DataMethod returns the data collection method when it is a string.
+func (c Channel) DataMethod() string {
+	method, _ := c.GetDataMethod.StringValue()
+
+	return method
+}
+
+// DataClass returns the class collecting the channel's data when it is a
+// string.
+func (c Channel) DataClass() string {
+	class, _ := c.ClassData.StringValue()
+
+	return class
+}
+
+// Deleted reports whether the channel has been deleted.
+func (c Channel) Deleted() bool {
+	return !c.DeletedAt.IsZero()
 }
 
 type Placement struct {
-	ID                  int         `json:"id"`
-	Title               string      `json:"title"`
-	Name                *string     `json:"name"`
-	IsActive            bool        `json:"isActive"`
-	IsVisible           bool        `json:"isVisible"`
-	IsDefaultForChannel bool        `json:"is_default_for_channel"`
-	ParentChannelID     int         `json:"parent_channel_id"`
-	NumLevel            int         `json:"num_level"`
-	CatID               string      `json:"cat_id"`
-	ServiceID           *int        `json:"service_id"`
-	ClientID            int         `json:"client_id"`
-	GroupingID          int         `json:"grouping_id"`
-	ClassData           *string     `json:"classData"`
-	GetDataMethod       interface{} `json:"getDataMethod"`
-	DateCreate          string      `json:"date_create"`
-	Sort                int         `json:"sort"`
-	CreatedAt           *string     `json:"created_at"`
-	UpdatedAt           *string     `json:"updated_at"`
-	DeletedAt           interface{} `json:"deleted_at"`
-	ChannelID           int         `json:"channel_id"`
+	ID                  int      `json:"id"`
+	Title               string   `json:"title"`
+	Name                *string  `json:"name"`
+	IsActive            FlexBool `json:"isActive"`
+	IsVisible           FlexBool `json:"isVisible"`
+	IsDefaultForChannel FlexBool `json:"is_default_for_channel"`
+	ParentChannelID     FlexInt  `json:"parent_channel_id"`
+	NumLevel            int      `json:"num_level"`
+	CatID               string   `json:"cat_id"`
+	ServiceID           *FlexInt `json:"service_id"`
+	ClientID            int      `json:"client_id"`
+	GroupingID          int      `json:"grouping_id"`
+	ClassData           RawJSON  `json:"classData"`
+	GetDataMethod       RawJSON  `json:"getDataMethod"`
+	DateCreate          FlexTime `json:"date_create"`
+	Sort                int      `json:"sort"`
+	CreatedAt           FlexTime `json:"created_at"`
+	UpdatedAt           FlexTime `json:"updated_at"`
+	DeletedAt           FlexTime `json:"deleted_at"`
+	ChannelID           int      `json:"channel_id"`
 	Channel             struct {
 		ID        int    `json:"id"`
 		Title     string `json:"title"`
@@ -98,12 +118,32 @@ type Placement struct {
 	} `json:"channel"`
 }
 
+// DataMethod returns the data collection method when it is a string.
+func (p Placement) DataMethod() string {
+	method, _ := p.GetDataMethod.StringValue()
+
+	return method
+}
+
+// DataClass returns the class collecting the placement's data when it is a
+// string.
+func (p Placement) DataClass() string {
+	class, _ := p.ClassData.StringValue()
+
+	return class
+}
+
+// Deleted reports whether the placement has been deleted.
+func (p Placement) Deleted() bool {
+	return !p.DeletedAt.IsZero()
+}
+
 type Campaign struct {
-	Id          int     `json:"id"`
-	PlacementId int     `json:"placement_id"`
-	Title       string  `json:"title"`
-	CreatedAt   *string `json:"created_at"`
-	UpdatedAt   *string `json:"updated_at"`
+	Id          int      `json:"id"`
+	PlacementId int      `json:"placement_id"`
+	Title       string   `json:"title"`
+	CreatedAt   FlexTime `json:"created_at"`
+	UpdatedAt   FlexTime `json:"updated_at"`
 }
 
 type Keyword struct {
@@ -112,91 +152,141 @@ type Keyword struct {
 }
 
 type CrmCustomField struct {
-	ID                int    `json:"id"`
-	CRMAccountID      int    `json:"crm_account_id"`
-	ElementTypeID     int    `json:"element_type_id"`
-	CustomFieldTitle  string `json:"custom_field_title"`
-	FieldTypeID       int    `json:"field_type_id"`
-	IsMultiple        int    `json:"is_multiple"`
-	GroupID           int    `json:"group_id"`
-	Description       string `json:"description"`
-	Status            int    `json:"status"`
-	IsFilter          int    `json:"is_filter"`
-	FilterParamID     int    `json:"filter_param_id"`
-	DefaultVisibility int    `json:"default_visibility"`
+	ID                int      `json:"id"`
+	CRMAccountID      int      `json:"crm_account_id"`
+	ElementTypeID     int      `json:"element_type_id"`
+	CustomFieldTitle  string   `json:"custom_field_title"`
+	FieldTypeID       int      `json:"field_type_id"`
+	IsMultiple        FlexBool `json:"is_multiple"`
+	GroupID           int      `json:"group_id"`
+	Description       string   `json:"description"`
+	Status            int      `json:"status"`
+	IsFilter          FlexBool `json:"is_filter"`
+	FilterParamID     int      `json:"filter_param_id"`
+	DefaultVisibility FlexBool `json:"default_visibility"`
 }
 
 type CrmCustomFieldGroup struct {
-	ID                int    `json:"id"`
-	Title             string `json:"title"`
-	CRMAccountID      int    `json:"crm_account_id"`
-	DefaultVisibility int    `json:"default_visibility"`
-	Sort              int    `json:"sort"`
+	ID                int      `json:"id"`
+	Title             string   `json:"title"`
+	CRMAccountID      int      `json:"crm_account_id"`
+	DefaultVisibility FlexBool `json:"default_visibility"`
+	Sort              int      `json:"sort"`
 }
 
 type Ad struct {
-	ID                 int         `json:"id"`
-	ExternalID         string      `json:"external_id"`
-	PlacementID        int         `json:"placement_id"`
-	CampaignID         int         `json:"campaign_id"`
-	ExternalCampaignID string      `json:"external_campaign_id"`
-	Type               string      `json:"type"`
-	Title              string      `json:"title"`
-	Text               string      `json:"text"`
-	Text1              string      `json:"text1"`
-	Text2              *string     `json:"text2"`
-	PreviewUrl         interface{} `json:"preview_url"`
-	Href               interface{} `json:"href"`
-	Device             interface{} `json:"device"`
-	CreatedAt          string      `json:"created_at"`
+	ID                 int      `json:"id"`
+	ExternalID         string   `json:"external_id"`
+	PlacementID        int      `json:"placement_id"`
+	CampaignID         int      `json:"campaign_id"`
+	ExternalCampaignID string   `json:"external_campaign_id"`
+	Type               string   `json:"type"`
+	Title              string   `json:"title"`
+	Text               string   `json:"text"`
+	Text1              string   `json:"text1"`
+	Text2              *string  `json:"text2"`
+	PreviewUrl         RawJSON  `json:"preview_url"`
+	Href               RawJSON  `json:"href"`
+	Device             RawJSON  `json:"device"`
+	CreatedAt          FlexTime `json:"created_at"`
+}
+
+// HrefURL returns the ad link. Href is usually a string; for a list of
+// links the first one is returned.
+func (a Ad) HrefURL() string {
+	if href, ok := a.Href.StringValue(); ok {
+		return href
+	}
+
+	var hrefs []string
+
+	err := a.Href.Decode(&hrefs)
+	if err != nil || len(hrefs) == 0 {
+		return ""
+	}
+
+	return hrefs[0]
+}
+
+// PreviewURL returns the preview image link. Like Href, the preview is
+// usually a string; for a list of links the first one is returned. An
+// object gives its "url" field, or "" if it has none.
+func (a Ad) PreviewURL() string {
+	if strings.HasPrefix(string(a.PreviewUrl), "{") {
+		var preview struct {
+			URL string `json:"url"`
+		}
+
+		err := a.PreviewUrl.Decode(&preview)
+		if err != nil {
+			return ""
+		}
+
+		return preview.URL
+	}
+
+	previews := a.PreviewUrl.Strings()
+	if len(previews) == 0 {
+		return ""
+	}
+
+	return previews[0]
+}
+
+// Devices returns the devices the ad targets. Device is a string for most
+// ads and a list for ads shown on several devices.
+func (a Ad) Devices() []string {
+	return a.Device.Strings()
 }
 
 type Project struct {
-	ID                   int    `json:"id"`
-	Project              string `json:"project"`
-	Title                string `json:"title"`
-	CreatedAt            int    `json:"created_at"`
-	IsActive             int    `json:"is_active"`
-	IsSuperObject        int    `json:"is_super_object"`
-	CanGroupingByObjects int    `json:"can_grouping_by_objects"`
-	ProjectFields        []struct {
-		Value string `json:"value"`
-		Title string `json:"title"`
-	} `json:"project_fields"`
+	ID                   int            `json:"id"`
+	Project              string         `json:"project"`
+	Title                string         `json:"title"`
+	CreatedAt            FlexTime       `json:"created_at"`
+	IsActive             FlexBool       `json:"is_active"`
+	IsSuperObject        FlexBool       `json:"is_super_object"`
+	CanGroupingByObjects FlexBool       `json:"can_grouping_by_objects"`
+	ProjectFields        []ProjectField `json:"project_fields"`
+}
+
+type ProjectField struct {
+	Value string `json:"value"`
+	Title string `json:"title"`
 }
 
 type Metric struct {
-	ID                 int     `json:"id"`
-	Code               string  `json:"code"`
-	Title              string  `json:"title"`
-	Description        *string `json:"description"`
-	CategoryID         int     `json:"category_id"`
-	CategoryTitle      string  `json:"category_title"`
-	CategorySort       int     `json:"category_sort"`
-	IsSystem           int     `json:"is_system"`
-	MParent            int     `json:"m_parent"`
-	ServiceID          int     `json:"service_id"`
-	IsGroup            *int    `json:"is_group"`
-	Formule            *string `json:"formule"`
-	Calculate          string  `json:"calculate"`
-	DateCreate         int     `json:"date_create"`
-	EnableOriginalData int     `json:"enable_original_data"`
+	ID                 int      `json:"id"`
+	Code               string   `json:"code"`
+	Title              string   `json:"title"`
+	Description        *string  `json:"description"`
+	CategoryID         int      `json:"category_id"`
+	CategoryTitle      string   `json:"category_title"`
+	CategorySort       int      `json:"category_sort"`
+	IsSystem           FlexBool `json:"is_system"`
+	MParent            FlexInt  `json:"m_parent"`
+	ServiceID          FlexInt  `json:"service_id"`
+	IsGroup            FlexBool `json:"is_group"`
+	Formule            *string  `json:"formule"`
+	Calculate          string   `json:"calculate"`
+	DateCreate         FlexTime `json:"date_create"`
+	EnableOriginalData FlexBool `json:"enable_original_data"`
 }
 
 type Grouping struct {
-	Id       int    `json:"id"`
-	Title    string `json:"title"`
-	Code     string `json:"code"`
-	IsSystem bool   `json:"is_system"`
-	Sort     int    `json:"sort"`
-	ClientId int    `json:"client_id"`
+	Id       int      `json:"id"`
+	Title    string   `json:"title"`
+	Code     string   `json:"code"`
+	IsSystem FlexBool `json:"is_system"`
+	Sort     int      `json:"sort"`
+	ClientId int      `json:"client_id"`
 }
 
 type AttributionSmartis struct {
-	About    string `json:"about"`
-	ID       int    `json:"id"`
-	IsSystem bool   `json:"is_system"`
-	Title    string `json:"title"`
+	About    string   `json:"about"`
+	ID       int      `json:"id"`
+	IsSystem FlexBool `json:"is_system"`
+	Title    string   `json:"title"`
 }
 
 type Attribution struct {
@@ -10,6 +10,29 @@ func (e *APIError) Error() string {
 	return e.Msg
 }
 
+// ErrNoReportData is returned by GetReport when the API returns no reports.
+var ErrNoReportData = errors.New("no reports data")
+
+// ErrUnknownAttributionModel is returned when an attribution model is not
+// in the AttributionRegistry.
+var ErrUnknownAttributionModel = errors.New("unknown attribution model")
+
+// ErrProjectNotFound is returned when a project code is not among the
+// account's projects.
+var ErrProjectNotFound = errors.New("project not found")
+
+// ErrObjectGroupingNotAllowed is returned when a project cannot be grouped
+// by GroupByObject.
+var ErrObjectGroupingNotAllowed = errors.New("grouping by objects is not allowed for project")
+
+// ErrEmptyCRMToken is returned by the CRM custom field requests when no
+// CRM token is configured for the call.
+var ErrEmptyCRMToken = errors.New("crm token is empty")
+
+// ErrSecretNotFound is returned by LocalVault for unknown paths and
+// versions.
+var ErrSecretNotFound = errors.New("secret not found")
+
 var (
 	errInternalError = errors.New("internal error")
 	errUnauthorized  = errors.New("unauthorized")
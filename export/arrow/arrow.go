@@ -0,0 +1,413 @@
+// Package arrow converts Smartis reports to Apache Arrow record batches and
+// streams them in the Arrow IPC format.
+//
+// Column types are inferred with gosmartis.InferColumns and refined with
+// metric metadata from Client.GetMetrics: columns named after a metric code
+// are always float64 measures. Dimension columns holding strings or
+// integers are dictionary-encoded.
+//
+// The conversion is the package function ToArrow rather than a
+// Report.ToArrow method: this package imports gosmartis, so a method would
+// need an import cycle, and keeping it here keeps the Arrow module out of
+// the dependencies of programs that only use the client.
+package arrow
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/ipc"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+
+	"github.com/zfullio/gosmartis"
+)
+
+// Field metadata keys.
+const (
+	MetadataColumnID    = "gosmartis.column_id"
+	MetadataName        = "gosmartis.name"
+	MetadataMetricTitle = "gosmartis.metric_title"
+	MetadataMetric      = "gosmartis.metric"
+)
+
+// DefaultBatchSize is the number of rows per record batch used when
+// Options.BatchSize is zero.
+const DefaultBatchSize = 64 * 1024
+
+var errClosed = errors.New("arrow: writer is closed")
+
+type Options struct {
+	// Metrics from Client.GetMetrics type the metric columns.
+	Metrics []gosmartis.Metric
+	// Allocator defaults to memory.DefaultAllocator.
+	Allocator memory.Allocator
+	// BatchSize limits the number of rows per record batch written by Writer.
+	BatchSize int
+	// Metric is stored in the schema metadata. ToArrow and WriteReport fill it in.
+	Metric string
+}
+
+func (o Options) allocator() memory.Allocator {
+	if o.Allocator == nil {
+		return memory.DefaultAllocator
+	}
+
+	return o.Allocator
+}
+
+// Columns infers the columns of the report and applies metric metadata.
+func Columns(report *gosmartis.Report, metrics []gosmartis.Metric) []gosmartis.ColumnInfo {
+	return ApplyMetrics(report.ColumnInfos(), metrics)
+}
+
+// ApplyMetrics marks columns named after a metric code as float measures.
+func ApplyMetrics(columns []gosmartis.ColumnInfo, metrics []gosmartis.Metric) []gosmartis.ColumnInfo {
+	codes := make(map[string]gosmartis.Metric, len(metrics))
+	for _, metric := range metrics {
+		codes[metric.Code] = metric
+	}
+
+	result := make([]gosmartis.ColumnInfo, 0, len(columns))
+
+	for _, col := range columns {
+		if _, ok := codes[col.ID]; ok {
+			col.Kind = gosmartis.ColumnFloat
+			col.Dimension = false
+		}
+
+		result = append(result, col)
+	}
+
+	return result
+}
+
+// NewSchema builds an Arrow schema for the given columns.
+func NewSchema(columns []gosmartis.ColumnInfo, opts Options) *arrow.Schema {
+	titles := make(map[string]string, len(opts.Metrics))
+	for _, metric := range opts.Metrics {
+		titles[metric.Code] = metric.Title
+	}
+
+	fields := make([]arrow.Field, 0, len(columns))
+
+	for _, col := range columns {
+		keys := []string{MetadataColumnID}
+		values := []string{col.ID}
+
+		if col.Name != "" {
+			keys = append(keys, MetadataName)
+			values = append(values, col.Name)
+		}
+
+		if title, ok := titles[col.ID]; ok {
+			keys = append(keys, MetadataMetricTitle)
+			values = append(values, title)
+		}
+
+		fields = append(fields, arrow.Field{
+			Name:     col.ID,
+			Type:     dataType(col),
+			Nullable: true,
+			Metadata: arrow.NewMetadata(keys, values),
+		})
+	}
+
+	var metadata *arrow.Metadata
+
+	if opts.Metric != "" {
+		md := arrow.NewMetadata([]string{MetadataMetric}, []string{opts.Metric})
+		metadata = &md
+	}
+
+	return arrow.NewSchema(fields, metadata)
+}
+
+func dataType(col gosmartis.ColumnInfo) arrow.DataType {
+	switch col.Kind {
+	case gosmartis.ColumnInt:
+		if col.Dimension {
+			return &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int32, ValueType: arrow.PrimitiveTypes.Int64}
+		}
+
+		return arrow.PrimitiveTypes.Int64
+	case gosmartis.ColumnFloat:
+		return arrow.PrimitiveTypes.Float64
+	case gosmartis.ColumnBool:
+		return arrow.FixedWidthTypes.Boolean
+	case gosmartis.ColumnDate:
+		return arrow.FixedWidthTypes.Date32
+	case gosmartis.ColumnDateTime:
+		return arrow.FixedWidthTypes.Timestamp_ms
+	default:
+		if col.Dimension {
+			return &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int32, ValueType: arrow.BinaryTypes.String}
+		}
+
+		return arrow.BinaryTypes.String
+	}
+}
+
+// ToArrow converts the report to a single record batch.
+// The caller must Release the record.
+func ToArrow(report *gosmartis.Report, opts Options) (arrow.Record, error) {
+	if opts.Metric == "" {
+		opts.Metric = report.Metric
+	}
+
+	columns := Columns(report, opts.Metrics)
+
+	b := newBatchBuilder(columns, opts)
+	defer b.release()
+
+	for _, row := range report.RowsMassive {
+		err := b.append(row)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return b.builder.NewRecord(), nil
+}
+
+// WriteReport streams the report to w in the Arrow IPC stream format.
+func WriteReport(w io.Writer, report *gosmartis.Report, opts Options) error {
+	if opts.Metric == "" {
+		opts.Metric = report.Metric
+	}
+
+	writer := NewWriter(w, Columns(report, opts.Metrics), opts)
+
+	err := writer.Write(report.RowsMassive...)
+	if err != nil {
+		writer.Close()
+
+		return err
+	}
+
+	return writer.Close()
+}
+
+// Writer streams report rows to an Arrow IPC stream, one record batch per
+// Options.BatchSize rows.
+type Writer struct {
+	batch     *batchBuilder
+	ipc       *ipc.Writer
+	batchSize int
+	pending   int
+	closed    bool
+}
+
+// NewWriter starts an Arrow IPC stream on w for rows with the given columns.
+// Close must be called to flush the last batch and end the stream.
+func NewWriter(w io.Writer, columns []gosmartis.ColumnInfo, opts Options) *Writer {
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	batch := newBatchBuilder(columns, opts)
+
+	return &Writer{
+		batch:     batch,
+		ipc:       ipc.NewWriter(w, ipc.WithSchema(batch.schema), ipc.WithAllocator(opts.allocator())),
+		batchSize: batchSize,
+	}
+}
+
+// Write appends rows to the stream, flushing a record batch whenever
+// BatchSize rows have been collected.
+func (w *Writer) Write(rows ...gosmartis.Row) error {
+	if w.closed {
+		return errClosed
+	}
+
+	for _, row := range rows {
+		err := w.batch.append(row)
+		if err != nil {
+			return err
+		}
+
+		w.pending++
+
+		if w.pending >= w.batchSize {
+			err = w.Flush()
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Flush writes the collected rows as a record batch.
+func (w *Writer) Flush() error {
+	if w.closed {
+		return errClosed
+	}
+
+	if w.pending == 0 {
+		return nil
+	}
+
+	record := w.batch.builder.NewRecord()
+	defer record.Release()
+
+	w.pending = 0
+
+	return w.ipc.Write(record)
+}
+
+// Close flushes the last batch and ends the stream.
+// It does not close the underlying io.Writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+
+	err := w.Flush()
+
+	w.closed = true
+	w.batch.release()
+
+	if closeErr := w.ipc.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+type batchBuilder struct {
+	columns []gosmartis.ColumnInfo
+	schema  *arrow.Schema
+	builder *array.RecordBuilder
+	index   map[string]int
+	present []bool
+}
+
+func newBatchBuilder(columns []gosmartis.ColumnInfo, opts Options) *batchBuilder {
+	schema := NewSchema(columns, opts)
+
+	index := make(map[string]int, len(columns))
+	for i, col := range columns {
+		index[col.ID] = i
+	}
+
+	return &batchBuilder{
+		columns: columns,
+		schema:  schema,
+		builder: array.NewRecordBuilder(opts.allocator(), schema),
+		index:   index,
+		present: make([]bool, len(columns)),
+	}
+}
+
+func (b *batchBuilder) release() {
+	b.builder.Release()
+}
+
+// append adds one row. Missing cells become nulls; cells whose ColumnID is
+// not part of the schema are skipped.
+func (b *batchBuilder) append(row gosmartis.Row) error {
+	for i := range b.present {
+		b.present[i] = false
+	}
+
+	for _, cell := range row {
+		i, ok := b.index[cell.ColumnID]
+		if !ok || b.present[i] {
+			continue
+		}
+
+		b.present[i] = true
+
+		err := appendValue(b.builder.Field(i), b.columns[i], cell.Value)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, ok := range b.present {
+		if !ok {
+			b.builder.Field(i).AppendNull()
+		}
+	}
+
+	return nil
+}
+
+func appendValue(builder array.Builder, col gosmartis.ColumnInfo, value interface{}) error {
+	if value == nil {
+		builder.AppendNull()
+
+		return nil
+	}
+
+	switch b := builder.(type) {
+	case *array.Int64DictionaryBuilder:
+		if n, ok := gosmartis.CellInt(value); ok {
+			return b.Append(n)
+		}
+	case *array.Int64Builder:
+		if n, ok := gosmartis.CellInt(value); ok {
+			b.Append(n)
+
+			return nil
+		}
+	case *array.Float64Builder:
+		if f, ok := gosmartis.CellFloat(value); ok {
+			b.Append(f)
+
+			return nil
+		}
+	case *array.BooleanBuilder:
+		if v, ok := value.(bool); ok {
+			b.Append(v)
+
+			return nil
+		}
+	case *array.Date32Builder:
+		if t, ok := timeValue(value, gosmartis.ParseDate); ok {
+			b.Append(arrow.Date32FromTime(t))
+
+			return nil
+		}
+	case *array.TimestampBuilder:
+		if t, ok := timeValue(value, gosmartis.ParseDateTime); ok {
+			b.Append(arrow.Timestamp(t.UnixMilli()))
+
+			return nil
+		}
+	case *array.BinaryDictionaryBuilder:
+		return b.AppendString(stringValue(value))
+	case *array.StringBuilder:
+		b.Append(stringValue(value))
+
+		return nil
+	}
+
+	return fmt.Errorf("arrow: column %s: cannot store %T value %v as %s", col.ID, value, value, col.Kind)
+}
+
+func stringValue(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+
+	return fmt.Sprint(value)
+}
+
+func timeValue(value interface{}, parse func(string) (time.Time, bool)) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		return parse(v)
+	default:
+		return time.Time{}, false
+	}
+}
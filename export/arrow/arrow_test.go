@@ -0,0 +1,271 @@
+package arrow
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/apache/arrow/go/v17/arrow"
+	"github.com/apache/arrow/go/v17/arrow/array"
+	"github.com/apache/arrow/go/v17/arrow/ipc"
+	"github.com/apache/arrow/go/v17/arrow/memory"
+
+	"github.com/zfullio/gosmartis"
+)
+
+func testReport() *gosmartis.Report {
+	row := func(day string, adID float64, channel, created string, cost, leads interface{}) gosmartis.Row {
+		return gosmartis.Row{
+			gosmartis.NewCell("day", day, ""),
+			gosmartis.NewCell("ad_id", adID, ""),
+			gosmartis.NewCell("channel", channel, ""),
+			gosmartis.NewCell("created", created, ""),
+			gosmartis.NewCell("cost", cost, ""),
+			gosmartis.NewCell("leads", leads, ""),
+		}
+	}
+
+	return &gosmartis.Report{Metric: "cost", RowsMassive: []gosmartis.Row{
+		row("2024-01-01", 101, "search", "2024-01-01 10:30:00", 12.5, nil),
+		row("2024-01-02", 102, "social", "2024-01-02 08:00:00", 3.0, nil),
+		row("2024-01-03", 101, "search", "2024-01-03 00:00:01", nil, nil),
+		row("2024-01-04", 103, "email", "2024-01-04 23:59:59", 0.25, nil),
+		row("2024-01-05", 104, "video", "2024-01-05 12:00:00", 7.75, nil),
+	}}
+}
+
+var testMetrics = []gosmartis.Metric{
+	{Code: "cost", Title: "Расход"},
+	{Code: "leads", Title: "Заявки"},
+}
+
+func TestToArrowSchema(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	record, err := ToArrow(testReport(), Options{Metrics: testMetrics, Allocator: mem})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer record.Release()
+
+	want := map[string]arrow.DataType{
+		"day":     arrow.FixedWidthTypes.Date32,
+		"ad_id":   &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int32, ValueType: arrow.PrimitiveTypes.Int64},
+		"channel": &arrow.DictionaryType{IndexType: arrow.PrimitiveTypes.Int32, ValueType: arrow.BinaryTypes.String},
+		"created": arrow.FixedWidthTypes.Timestamp_ms,
+		"cost":    arrow.PrimitiveTypes.Float64,
+		// Without metrics an all-null column would be a string.
+		"leads": arrow.PrimitiveTypes.Float64,
+	}
+
+	schema := record.Schema()
+	if schema.NumFields() != len(want) {
+		t.Fatalf("schema = %s", schema)
+	}
+
+	for _, field := range schema.Fields() {
+		if !arrow.TypeEqual(field.Type, want[field.Name]) {
+			t.Errorf("%s: type %s, want %s", field.Name, field.Type, want[field.Name])
+		}
+	}
+
+	if md := schema.Metadata(); md.FindKey(MetadataMetric) < 0 || md.Values()[md.FindKey(MetadataMetric)] != "cost" {
+		t.Errorf("schema metadata = %v", md)
+	}
+
+	costs, _ := schema.FieldsByName("cost")
+	if md := costs[0].Metadata; md.Values()[md.FindKey(MetadataMetricTitle)] != "Расход" {
+		t.Errorf("cost metadata = %v", md)
+	}
+
+	if record.NumRows() != 5 {
+		t.Errorf("rows = %d", record.NumRows())
+	}
+
+	cost := record.Column(schema.FieldIndices("cost")[0]).(*array.Float64)
+	if cost.Value(0) != 12.5 || !cost.IsNull(2) || cost.Value(3) != 0.25 {
+		t.Errorf("cost = %v", cost)
+	}
+
+	day := record.Column(schema.FieldIndices("day")[0]).(*array.Date32)
+	if got := day.Value(1).ToTime().Format("2006-01-02"); got != "2024-01-02" {
+		t.Errorf("day[1] = %s", got)
+	}
+
+	created := record.Column(schema.FieldIndices("created")[0]).(*array.Timestamp)
+	if got := created.Value(0); int64(got) != 1704105000000 {
+		t.Errorf("created[0] = %d", got)
+	}
+
+	leads := record.Column(schema.FieldIndices("leads")[0])
+	if leads.NullN() != 5 {
+		t.Errorf("leads nulls = %d", leads.NullN())
+	}
+}
+
+func TestToArrowWithoutMetrics(t *testing.T) {
+	record, err := ToArrow(testReport(), Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer record.Release()
+
+	leads, _ := record.Schema().FieldsByName("leads")
+	if !arrow.TypeEqual(leads[0].Type, arrow.BinaryTypes.String) {
+		t.Errorf("leads without metrics = %s", leads[0].Type)
+	}
+}
+
+func TestDictionaryDimensions(t *testing.T) {
+	record, err := ToArrow(testReport(), Options{Metrics: testMetrics})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer record.Release()
+
+	channel := record.Column(record.Schema().FieldIndices("channel")[0]).(*array.Dictionary)
+	values := channel.Dictionary().(*array.String)
+
+	if values.Len() != 4 {
+		t.Errorf("channel dictionary has %d values, want 4 distinct", values.Len())
+	}
+
+	if channel.GetValueIndex(0) != channel.GetValueIndex(2) || values.Value(channel.GetValueIndex(0)) != "search" {
+		t.Errorf("channel indices = %v", channel)
+	}
+
+	adID := record.Column(record.Schema().FieldIndices("ad_id")[0]).(*array.Dictionary)
+	ids := adID.Dictionary().(*array.Int64)
+
+	if ids.Len() != 4 || ids.Value(adID.GetValueIndex(4)) != 104 {
+		t.Errorf("ad_id dictionary = %v", ids)
+	}
+}
+
+func TestWriterStream(t *testing.T) {
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+
+	report := testReport()
+	opts := Options{Metrics: testMetrics, Allocator: mem, BatchSize: 2, Metric: report.Metric}
+
+	var buf bytes.Buffer
+
+	w := NewWriter(&buf, Columns(report, opts.Metrics), opts)
+
+	for _, row := range report.RowsMassive {
+		err := w.Write(row)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Write(report.RowsMassive[0]); !errors.Is(err, errClosed) {
+		t.Errorf("Write after Close = %v", err)
+	}
+
+	if err := w.Flush(); !errors.Is(err, errClosed) {
+		t.Errorf("Flush after Close = %v", err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Errorf("second Close = %v", err)
+	}
+
+	reader, err := ipc.NewReader(&buf, ipc.WithAllocator(mem))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Release()
+
+	var (
+		sizes    []int64
+		channels []string
+		dictLens []int
+	)
+
+	for reader.Next() {
+		record := reader.Record()
+		sizes = append(sizes, record.NumRows())
+
+		channel := record.Column(record.Schema().FieldIndices("channel")[0]).(*array.Dictionary)
+		values := channel.Dictionary().(*array.String)
+		dictLens = append(dictLens, values.Len())
+
+		for i := 0; i < channel.Len(); i++ {
+			channels = append(channels, values.Value(channel.GetValueIndex(i)))
+		}
+	}
+
+	if err := reader.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sizes) != 3 || sizes[0] != 2 || sizes[1] != 2 || sizes[2] != 1 {
+		t.Errorf("batch sizes = %v, want [2 2 1]", sizes)
+	}
+
+	want := []string{"search", "social", "search", "email", "video"}
+	for i := range want {
+		if i >= len(channels) || channels[i] != want[i] {
+			t.Fatalf("channels = %v, want %v", channels, want)
+		}
+	}
+
+	// The dictionary carries over between batches and only grows.
+	for i := 1; i < len(dictLens); i++ {
+		if dictLens[i] < dictLens[i-1] {
+			t.Errorf("dictionary sizes = %v", dictLens)
+		}
+	}
+
+	if dictLens[len(dictLens)-1] != 4 {
+		t.Errorf("final dictionary size = %d, want 4", dictLens[len(dictLens)-1])
+	}
+
+	if md := reader.Schema().Metadata(); md.FindKey(MetadataMetric) < 0 {
+		t.Errorf("stream schema metadata = %v", md)
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	var buf bytes.Buffer
+
+	err := WriteReport(&buf, testReport(), Options{Metrics: testMetrics})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := ipc.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Release()
+
+	rows := int64(0)
+	for reader.Next() {
+		rows += reader.Record().NumRows()
+	}
+
+	if rows != 5 {
+		t.Errorf("rows = %d", rows)
+	}
+}
+
+func TestWriteRejectsBadValue(t *testing.T) {
+	columns := []gosmartis.ColumnInfo{{ID: "cost", Kind: gosmartis.ColumnFloat}}
+
+	w := NewWriter(&bytes.Buffer{}, columns, Options{})
+	defer w.Close()
+
+	err := w.Write(gosmartis.Row{gosmartis.NewCell("cost", "n/a", "")})
+	if err == nil {
+		t.Error("a string is stored in a float column")
+	}
+}
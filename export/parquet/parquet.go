@@ -0,0 +1,296 @@
+// Package parquet writes Smartis reports as Apache Parquet files.
+//
+// The columnar schema is inferred from the report with
+// gosmartis.InferColumns: every column is optional, dimension columns use
+// dictionary encoding and metrics are stored as doubles.
+package parquet
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	pq "github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress"
+
+	"github.com/zfullio/gosmartis"
+)
+
+const (
+	// DefaultRowGroupSize is the number of rows per row group used when
+	// Options.RowGroupSize is zero.
+	DefaultRowGroupSize = 128 * 1024
+
+	// Key-value metadata written to every file.
+	MetadataMetric      = "gosmartis.metric"
+	MetadataColumnNames = "gosmartis.column_names"
+)
+
+var errClosed = errors.New("parquet: writer is closed")
+
+type Options struct {
+	// RowGroupSize limits the number of rows per row group.
+	RowGroupSize int64
+	// Compression defaults to Snappy.
+	Compression compress.Codec
+	// Metric is stored in the file metadata. WriteReport fills it in.
+	Metric string
+}
+
+// Schema maps report columns to Parquet leaf columns.
+type Schema struct {
+	columns []gosmartis.ColumnInfo
+	schema  *pq.Schema
+	// leaf maps a ColumnID to the index of its Parquet leaf column.
+	leaf map[string]int
+	// index maps a ColumnID to its position in columns.
+	index map[string]int
+}
+
+// NewSchema builds a Parquet schema for the given columns.
+func NewSchema(name string, columns []gosmartis.ColumnInfo) *Schema {
+	group := make(pq.Group, len(columns))
+
+	for _, col := range columns {
+		group[col.ID] = columnNode(col)
+	}
+
+	schema := pq.NewSchema(name, group)
+
+	// Group fields are ordered by name, not by the order of columns.
+	leaf := make(map[string]int, len(columns))
+	for i, path := range schema.Columns() {
+		leaf[path[0]] = i
+	}
+
+	index := make(map[string]int, len(columns))
+	for i, col := range columns {
+		index[col.ID] = i
+	}
+
+	return &Schema{
+		columns: append([]gosmartis.ColumnInfo(nil), columns...),
+		schema:  schema,
+		leaf:    leaf,
+		index:   index,
+	}
+}
+
+// InferSchema builds a schema from the columns of the given rows.
+func InferSchema(name string, rows []gosmartis.Row) *Schema {
+	return NewSchema(name, gosmartis.InferColumns(rows))
+}
+
+// Columns returns the report columns covered by the schema.
+func (s *Schema) Columns() []gosmartis.ColumnInfo {
+	return append([]gosmartis.ColumnInfo(nil), s.columns...)
+}
+
+// Parquet returns the underlying Parquet schema.
+func (s *Schema) Parquet() *pq.Schema {
+	return s.schema
+}
+
+func columnNode(col gosmartis.ColumnInfo) pq.Node {
+	var node pq.Node
+
+	switch col.Kind {
+	case gosmartis.ColumnInt:
+		node = pq.Int(64)
+	case gosmartis.ColumnFloat:
+		node = pq.Leaf(pq.DoubleType)
+	case gosmartis.ColumnBool:
+		node = pq.Leaf(pq.BooleanType)
+	case gosmartis.ColumnDate:
+		node = pq.Date()
+	case gosmartis.ColumnDateTime:
+		node = pq.Timestamp(pq.Millisecond)
+	default:
+		node = pq.String()
+	}
+
+	if col.Dimension && col.Kind != gosmartis.ColumnBool {
+		node = pq.Encoded(node, &pq.RLEDictionary)
+	}
+
+	return pq.Optional(node)
+}
+
+// WriteReport writes the whole report to w as a single Parquet file.
+func WriteReport(w io.Writer, report *gosmartis.Report, opts Options) error {
+	if opts.Metric == "" {
+		opts.Metric = report.Metric
+	}
+
+	schema := InferSchema(report.Metric, report.RowsMassive)
+
+	writer := NewWriter(w, schema, opts)
+
+	err := writer.Write(report.RowsMassive...)
+	if err != nil {
+		return err
+	}
+
+	return writer.Close()
+}
+
+// Writer streams report rows into a Parquet file.
+// Rows are buffered only until a row group is full.
+type Writer struct {
+	schema *Schema
+	writer *pq.Writer
+	buf    []pq.Row
+	closed bool
+}
+
+// NewWriter starts a Parquet file with the given schema on w.
+// Close must be called to write the file footer.
+func NewWriter(w io.Writer, schema *Schema, opts Options) *Writer {
+	rowGroupSize := opts.RowGroupSize
+	if rowGroupSize <= 0 {
+		rowGroupSize = DefaultRowGroupSize
+	}
+
+	var codec compress.Codec = &pq.Snappy
+	if opts.Compression != nil {
+		codec = opts.Compression
+	}
+
+	writerOptions := []pq.WriterOption{
+		schema.schema,
+		pq.MaxRowsPerRowGroup(rowGroupSize),
+		pq.Compression(codec),
+		pq.KeyValueMetadata(MetadataColumnNames, columnNames(schema.columns)),
+	}
+
+	if opts.Metric != "" {
+		writerOptions = append(writerOptions, pq.KeyValueMetadata(MetadataMetric, opts.Metric))
+	}
+
+	return &Writer{
+		schema: schema,
+		writer: pq.NewWriter(w, writerOptions...),
+	}
+}
+
+// Write appends rows to the file. Cells whose ColumnID is not part of the
+// schema are skipped; values that do not fit the column type are an error.
+func (w *Writer) Write(rows ...gosmartis.Row) error {
+	if w.closed {
+		return errClosed
+	}
+
+	w.buf = w.buf[:0]
+
+	for _, row := range rows {
+		pqRow, err := w.schema.row(row)
+		if err != nil {
+			return err
+		}
+
+		w.buf = append(w.buf, pqRow)
+	}
+
+	_, err := w.writer.WriteRows(w.buf)
+
+	return err
+}
+
+// Close flushes buffered rows and writes the file footer.
+// It does not close the underlying io.Writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+
+	w.closed = true
+
+	return w.writer.Close()
+}
+
+func (s *Schema) row(row gosmartis.Row) (pq.Row, error) {
+	values := make(pq.Row, len(s.leaf))
+	for i := range values {
+		values[i] = pq.NullValue().Level(0, 0, i)
+	}
+
+	for _, cell := range row {
+		i, ok := s.leaf[cell.ColumnID]
+		if !ok || cell.Value == nil {
+			continue
+		}
+
+		value, err := convert(s.columns[s.index[cell.ColumnID]], cell.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		values[i] = value.Level(0, 1, i)
+	}
+
+	return values, nil
+}
+
+func convert(col gosmartis.ColumnInfo, value interface{}) (pq.Value, error) {
+	switch col.Kind {
+	case gosmartis.ColumnInt:
+		if n, ok := gosmartis.CellInt(value); ok {
+			return pq.Int64Value(n), nil
+		}
+	case gosmartis.ColumnFloat:
+		if f, ok := gosmartis.CellFloat(value); ok {
+			return pq.DoubleValue(f), nil
+		}
+	case gosmartis.ColumnBool:
+		if b, ok := value.(bool); ok {
+			return pq.BooleanValue(b), nil
+		}
+	case gosmartis.ColumnDate:
+		if t, ok := timeValue(value, gosmartis.ParseDate); ok {
+			return pq.Int32Value(int32(t.Unix() / int64(24*time.Hour/time.Second))), nil
+		}
+	case gosmartis.ColumnDateTime:
+		if t, ok := timeValue(value, gosmartis.ParseDateTime); ok {
+			return pq.Int64Value(t.UnixMilli()), nil
+		}
+	default:
+		if s, ok := value.(string); ok {
+			return pq.ByteArrayValue([]byte(s)), nil
+		}
+
+		return pq.ByteArrayValue([]byte(fmt.Sprint(value))), nil
+	}
+
+	return pq.Value{}, fmt.Errorf("parquet: column %s: cannot store %T value %v as %s", col.ID, value, value, col.Kind)
+}
+
+func timeValue(value interface{}, parse func(string) (time.Time, bool)) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		return parse(v)
+	default:
+		return time.Time{}, false
+	}
+}
+
+// columnNames encodes resolved column names as "id=name" pairs separated by
+// newlines, for readers that want the CRM field titles back.
+func columnNames(columns []gosmartis.ColumnInfo) string {
+	var names []byte
+
+	for _, col := range columns {
+		if col.Name == "" {
+			continue
+		}
+
+		names = append(names, col.ID...)
+		names = append(names, '=')
+		names = append(names, col.Name...)
+		names = append(names, '\n')
+	}
+
+	return string(names)
+}
@@ -0,0 +1,224 @@
+package parquet_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+
+	pq "github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/format"
+
+	"github.com/zfullio/gosmartis"
+	"github.com/zfullio/gosmartis/export/parquet"
+)
+
+func testReport() *gosmartis.Report {
+	rows := []gosmartis.Row{
+		{
+			gosmartis.NewCell("day", "2024-01-01", ""),
+			gosmartis.NewCell("ad_id", 101.0, ""),
+			gosmartis.NewCell("created", "2024-01-01 10:30:00", ""),
+			gosmartis.NewCell("cost", 12.5, ""),
+			gosmartis.NewCell("field_7", "web", "Source"),
+		},
+		{
+			gosmartis.NewCell("day", "2024-01-02", ""),
+			gosmartis.NewCell("ad_id", 102.0, ""),
+			gosmartis.NewCell("created", nil, ""),
+			gosmartis.NewCell("cost", nil, ""),
+			gosmartis.NewCell("field_7", "web", "Source"),
+		},
+		{
+			gosmartis.NewCell("day", "2024-01-03", ""),
+			gosmartis.NewCell("ad_id", 101.0, ""),
+			gosmartis.NewCell("created", "2024-01-03 00:00:01", ""),
+			gosmartis.NewCell("cost", 3.0, ""),
+		},
+		{
+			gosmartis.NewCell("day", "2024-01-04", ""),
+			gosmartis.NewCell("ad_id", 103.0, ""),
+			gosmartis.NewCell("created", "2024-01-04 23:59:59", ""),
+			gosmartis.NewCell("cost", 0.25, ""),
+			gosmartis.NewCell("field_7", "phone", "Source"),
+		},
+		{
+			gosmartis.NewCell("day", "2024-01-05", ""),
+			gosmartis.NewCell("ad_id", 101.0, ""),
+			gosmartis.NewCell("created", "2024-01-05 12:00:00", ""),
+			gosmartis.NewCell("cost", 7.75, ""),
+			gosmartis.NewCell("field_7", nil, "Source"),
+		},
+	}
+
+	return &gosmartis.Report{Metric: "cost", RowsMassive: rows}
+}
+
+func writeFile(t *testing.T, report *gosmartis.Report) *pq.File {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	err := parquet.WriteReport(&buf, report, parquet.Options{RowGroupSize: 2})
+	if err != nil {
+		t.Fatalf("WriteReport: %v", err)
+	}
+
+	file, err := pq.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("OpenFile: %v", err)
+	}
+
+	return file
+}
+
+func TestWriteReportRoundTrip(t *testing.T) {
+	report := testReport()
+	file := writeFile(t, report)
+
+	if got := file.NumRows(); got != int64(len(report.RowsMassive)) {
+		t.Fatalf("NumRows = %d, want %d", got, len(report.RowsMassive))
+	}
+
+	if got := len(file.RowGroups()); got != 3 {
+		t.Errorf("row groups = %d, want 3", got)
+	}
+
+	leaves := make(map[string]int)
+	for i, path := range file.Schema().Columns() {
+		leaves[path[0]] = i
+	}
+
+	reader := pq.NewReader(file)
+	defer reader.Close()
+
+	var got []pq.Row
+
+	for {
+		rows := make([]pq.Row, 1)
+
+		n, err := reader.ReadRows(rows)
+		if n > 0 {
+			got = append(got, rows[0].Clone())
+		}
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			t.Fatalf("ReadRows: %v", err)
+		}
+	}
+
+	if len(got) != len(report.RowsMassive) {
+		t.Fatalf("read %d rows, want %d", len(got), len(report.RowsMassive))
+	}
+
+	value := func(row int, column string) pq.Value {
+		return got[row][leaves[column]]
+	}
+
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	if v := value(1, "day"); v.Int32() != int32(day.Unix()/86400) {
+		t.Errorf("day = %d, want %d", v.Int32(), day.Unix()/86400)
+	}
+
+	if v := value(3, "ad_id"); v.Int64() != 103 {
+		t.Errorf("ad_id = %d, want 103", v.Int64())
+	}
+
+	created := time.Date(2024, 1, 4, 23, 59, 59, 0, time.UTC)
+	if v := value(3, "created"); v.Int64() != created.UnixMilli() {
+		t.Errorf("created = %d, want %d", v.Int64(), created.UnixMilli())
+	}
+
+	if v := value(4, "cost"); v.Double() != 7.75 {
+		t.Errorf("cost = %v, want 7.75", v.Double())
+	}
+
+	if v := value(3, "field_7"); string(v.ByteArray()) != "phone" {
+		t.Errorf("field_7 = %q, want phone", v.ByteArray())
+	}
+
+	nulls := []struct {
+		row    int
+		column string
+	}{
+		{1, "created"},
+		{1, "cost"},
+		{2, "field_7"},
+		{4, "field_7"},
+	}
+
+	for _, n := range nulls {
+		v := value(n.row, n.column)
+		if !v.IsNull() || v.DefinitionLevel() != 0 {
+			t.Errorf("row %d %s = %v (definition level %d), want null", n.row, n.column, v, v.DefinitionLevel())
+		}
+	}
+
+	if v := value(0, "cost"); v.IsNull() || v.DefinitionLevel() != 1 {
+		t.Errorf("row 0 cost definition level = %d, want 1", v.DefinitionLevel())
+	}
+}
+
+func TestWriteReportEncodingAndMetadata(t *testing.T) {
+	file := writeFile(t, testReport())
+
+	dictionary := map[string]bool{"day": true, "ad_id": true, "created": true, "field_7": true, "cost": false}
+
+	for _, group := range file.Metadata().RowGroups {
+		for _, chunk := range group.Columns {
+			column := chunk.MetaData.PathInSchema[0]
+
+			want, ok := dictionary[column]
+			if !ok {
+				t.Errorf("unexpected column %s", column)
+
+				continue
+			}
+
+			if got := hasDictionary(chunk.MetaData.Encoding); got != want {
+				t.Errorf("%s: dictionary encoding = %v, want %v (%v)", column, got, want, chunk.MetaData.Encoding)
+			}
+		}
+	}
+
+	for _, leaf := range file.Schema().Fields() {
+		if !leaf.Optional() {
+			t.Errorf("%s is not optional", leaf.Name())
+		}
+	}
+
+	if metric, ok := file.Lookup(parquet.MetadataMetric); !ok || metric != "cost" {
+		t.Errorf("%s = %q, %v; want cost", parquet.MetadataMetric, metric, ok)
+	}
+
+	if names, ok := file.Lookup(parquet.MetadataColumnNames); !ok || names != "field_7=Source\n" {
+		t.Errorf("%s = %q, %v; want field_7=Source", parquet.MetadataColumnNames, names, ok)
+	}
+}
+
+func hasDictionary(encodings []format.Encoding) bool {
+	for _, e := range encodings {
+		if e == format.RLEDictionary || e == format.PlainDictionary {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestInferSchemaAllNullMetric(t *testing.T) {
+	rows := []gosmartis.Row{
+		{gosmartis.NewCell("day", "2024-01-01", ""), gosmartis.NewCell("leads", nil, "")},
+		{gosmartis.NewCell("day", "2024-01-02", ""), gosmartis.NewCell("leads", nil, "")},
+	}
+
+	for _, col := range parquet.InferSchema("leads", rows).Columns() {
+		if col.ID == "leads" && col.Dimension {
+			t.Errorf("all-null metric leads is a dimension")
+		}
+	}
+}
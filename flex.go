@@ -0,0 +1,278 @@
+package gosmartis
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Smartis returns flags, numbers and timestamps in several encodings
+// depending on the endpoint. The Flex types accept all of them.
+
+var jsonNull = []byte("null")
+
+// FlexBool decodes true/false, 0/1, their string forms and null.
+type FlexBool bool
+
+func (b *FlexBool) UnmarshalJSON(data []byte) error {
+	s, err := flexString(data)
+	if err != nil {
+		return fmt.Errorf("flex bool: %w", err)
+	}
+
+	switch strings.ToLower(s) {
+	case "", "0", "false", "n", "no":
+		*b = false
+	case "1", "true", "y", "yes":
+		*b = true
+	default:
+		n, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("flex bool: invalid value %s", data)
+		}
+
+		*b = n != 0
+	}
+
+	return nil
+}
+
+func (b FlexBool) MarshalJSON() ([]byte, error) {
+	return json.Marshal(bool(b))
+}
+
+// FlexInt decodes numbers, numeric strings and null; empty values are 0.
+type FlexInt int
+
+func (i *FlexInt) UnmarshalJSON(data []byte) error {
+	s, err := flexString(data)
+	if err != nil {
+		return fmt.Errorf("flex int: %w", err)
+	}
+
+	if s == "" {
+		*i = 0
+
+		return nil
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		f, ferr := strconv.ParseFloat(s, 64)
+		if ferr != nil || f != float64(int64(f)) {
+			return fmt.Errorf("flex int: invalid value %s", data)
+		}
+
+		n = int64(f)
+	}
+
+	*i = FlexInt(n)
+
+	return nil
+}
+
+func (i FlexInt) MarshalJSON() ([]byte, error) {
+	return json.Marshal(int(i))
+}
+
+// FlexTime decodes Unix seconds, as a number or a string, and the date and
+// datetime layouts Smartis uses. Times without a zone are read as UTC.
+// Null, empty and all-zero values decode to the zero time.
+type FlexTime struct {
+	time.Time
+}
+
+var flexTimeLayouts = []string{
+	smartisDateTimeLayout,
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04:05.000000Z",
+	smartisDateLayout,
+}
+
+func (t *FlexTime) UnmarshalJSON(data []byte) error {
+	s, err := flexString(data)
+	if err != nil {
+		return fmt.Errorf("flex time: %w", err)
+	}
+
+	t.Time = time.Time{}
+
+	if s == "" || strings.Trim(s, "0-: ") == "" {
+		return nil
+	}
+
+	if sec, err := strconv.ParseInt(s, 10, 64); err == nil {
+		t.Time = time.Unix(sec, 0).UTC()
+
+		return nil
+	}
+
+	for _, layout := range flexTimeLayouts {
+		parsed, err := time.Parse(layout, s)
+		if err == nil {
+			t.Time = parsed
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("flex time: invalid value %s", data)
+}
+
+// MarshalJSON writes RFC 3339, or null for the zero time.
+func (t FlexTime) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return jsonNull, nil
+	}
+
+	return json.Marshal(t.Time.Format(time.RFC3339))
+}
+
+// flexString returns a JSON scalar as text: strings unquoted, null empty.
+func flexString(data []byte) (string, error) {
+	data = bytes.TrimSpace(data)
+
+	if bytes.Equal(data, jsonNull) {
+		return "", nil
+	}
+
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+
+		err := json.Unmarshal(data, &s)
+
+		return strings.TrimSpace(s), err
+	}
+
+	if len(data) > 0 && (data[0] == '{' || data[0] == '[') {
+		return "", fmt.Errorf("unexpected %s", data)
+	}
+
+	return string(data), nil
+}
+
+// FlexString decodes strings and scalars as their text; null is empty.
+type FlexString string
+
+func (s *FlexString) UnmarshalJSON(data []byte) error {
+	text, err := flexString(data)
+	if err != nil {
+		return fmt.Errorf("flex string: %w", err)
+	}
+
+	*s = FlexString(text)
+
+	return nil
+}
+
+// MarshalJSON writes null for the empty string.
+func (s FlexString) MarshalJSON() ([]byte, error) {
+	if s == "" {
+		return jsonNull, nil
+	}
+
+	return json.Marshal(string(s))
+}
+
+// RawJSON keeps a value whose shape varies as its JSON text. Unlike
+// json.RawMessage it is comparable; null is stored as "".
+type RawJSON string
+
+func (r *RawJSON) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, jsonNull) {
+		*r = ""
+
+		return nil
+	}
+
+	*r = RawJSON(data)
+
+	return nil
+}
+
+func (r RawJSON) MarshalJSON() ([]byte, error) {
+	if r == "" {
+		return jsonNull, nil
+	}
+
+	return []byte(r), nil
+}
+
+// IsNull reports whether the value is null or missing.
+func (r RawJSON) IsNull() bool {
+	return r == ""
+}
+
+// StringValue returns the value if it is a JSON string.
+func (r RawJSON) StringValue() (string, bool) {
+	if !strings.HasPrefix(string(r), `"`) {
+		return "", false
+	}
+
+	var s string
+
+	err := json.Unmarshal([]byte(r), &s)
+
+	return s, err == nil
+}
+
+// Text returns a string value unquoted and any other value as JSON.
+func (r RawJSON) Text() string {
+	if s, ok := r.StringValue(); ok {
+		return s
+	}
+
+	return string(r)
+}
+
+// Strings returns a string value as a single element and the elements of
+// an array as their Text. Null, empty strings and empty arrays give nil;
+// any other value is returned as its JSON text.
+func (r RawJSON) Strings() []string {
+	if r == "" {
+		return nil
+	}
+
+	if s, ok := r.StringValue(); ok {
+		if s == "" {
+			return nil
+		}
+
+		return []string{s}
+	}
+
+	var items []RawJSON
+
+	err := json.Unmarshal([]byte(r), &items)
+	if err != nil {
+		return []string{string(r)}
+	}
+
+	result := make([]string, 0, len(items))
+
+	for _, item := range items {
+		if text := item.Text(); text != "" {
+			result = append(result, text)
+		}
+	}
+
+	if len(result) == 0 {
+		return nil
+	}
+
+	return result
+}
+
+// Decode unmarshals the value into v.
+func (r RawJSON) Decode(v interface{}) error {
+	if r == "" {
+		return nil
+	}
+
+	return json.Unmarshal([]byte(r), v)
+}
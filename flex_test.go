@@ -0,0 +1,403 @@
+package gosmartis
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFlexBool(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    FlexBool
+		wantErr bool
+	}{
+		{`true`, true, false},
+		{`false`, false, false},
+		{`1`, true, false},
+		{`0`, false, false},
+		{`"1"`, true, false},
+		{`"0"`, false, false},
+		{`"true"`, true, false},
+		{`"False"`, false, false},
+		{`"yes"`, true, false},
+		{`"n"`, false, false},
+		{`null`, false, false},
+		{`""`, false, false},
+		{`" 1 "`, true, false},
+		{`"2"`, true, false},
+		{`"0.0"`, false, false},
+		{`-1`, true, false},
+		{`"maybe"`, false, true},
+		{`[1]`, false, true},
+		{`{}`, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			// Start from the opposite value to see that decoding overwrites it.
+			got := !tt.want
+
+			err := json.Unmarshal([]byte(tt.in), &got)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("want an error, got %v", got)
+				}
+
+				return
+			}
+
+			if err != nil || got != tt.want {
+				t.Errorf("got %v, %v; want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlexInt(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    FlexInt
+		wantErr bool
+	}{
+		{`12`, 12, false},
+		{`-3`, -3, false},
+		{`"12"`, 12, false},
+		{`12.0`, 12, false},
+		{`"7.0"`, 7, false},
+		{`1e3`, 1000, false},
+		{`null`, 0, false},
+		{`""`, 0, false},
+		{`0`, 0, false},
+		{`12.5`, 0, true},
+		{`"abc"`, 0, true},
+		{`true`, 0, true},
+		{`[1]`, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got := FlexInt(99)
+
+			err := json.Unmarshal([]byte(tt.in), &got)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("want an error, got %v", got)
+				}
+
+				return
+			}
+
+			if err != nil || got != tt.want {
+				t.Errorf("got %v, %v; want %v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlexTime(t *testing.T) {
+	msk := time.FixedZone("", 3*60*60)
+
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{`1700000000`, time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC), false},
+		{`"1700000000"`, time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC), false},
+		{`"2024-01-31"`, time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), false},
+		{`"2024-01-31 23:59:58"`, time.Date(2024, 1, 31, 23, 59, 58, 0, time.UTC), false},
+		{`"2024-01-31T23:59:58"`, time.Date(2024, 1, 31, 23, 59, 58, 0, time.UTC), false},
+		{`"2024-01-31T23:59:58+03:00"`, time.Date(2024, 1, 31, 23, 59, 58, 0, msk), false},
+		{`"2024-01-31T23:59:58.123Z"`, time.Date(2024, 1, 31, 23, 59, 58, 123000000, time.UTC), false},
+		{`"2024-01-31T23:59:58.000000Z"`, time.Date(2024, 1, 31, 23, 59, 58, 0, time.UTC), false},
+		{`"0000-00-00 00:00:00"`, time.Time{}, false},
+		{`"0000-00-00"`, time.Time{}, false},
+		{`null`, time.Time{}, false},
+		{`""`, time.Time{}, false},
+		{`"31.01.2024"`, time.Time{}, true},
+		{`{}`, time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got := FlexTime{Time: time.Now()}
+
+			err := json.Unmarshal([]byte(tt.in), &got)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("want an error, got %v", got)
+				}
+
+				return
+			}
+
+			if err != nil || !got.Equal(tt.want) {
+				t.Errorf("got %v, %v; want %v", got.Time, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlexMarshal(t *testing.T) {
+	v := struct {
+		B FlexBool   `json:"b"`
+		I FlexInt    `json:"i"`
+		T FlexTime   `json:"t"`
+		Z FlexTime   `json:"z"`
+		S FlexString `json:"s"`
+		E FlexString `json:"e"`
+		R RawJSON    `json:"r"`
+		N RawJSON    `json:"n"`
+	}{
+		B: true,
+		I: 5,
+		T: FlexTime{time.Date(2024, 1, 31, 12, 0, 0, 0, time.UTC)},
+		S: "x",
+		R: `[1,2]`,
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"b":true,"i":5,"t":"2024-01-31T12:00:00Z","z":null,"s":"x","e":null,"r":[1,2],"n":null}`
+	if string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+}
+
+func TestFlexString(t *testing.T) {
+	for in, want := range map[string]FlexString{`"a"`: "a", `12`: "12", `true`: "true", `null`: ""} {
+		var got FlexString
+
+		err := json.Unmarshal([]byte(in), &got)
+		if err != nil || got != want {
+			t.Errorf("%s: got %q, %v; want %q", in, got, err, want)
+		}
+	}
+}
+
+func TestRawJSON(t *testing.T) {
+	var r RawJSON
+
+	if err := json.Unmarshal([]byte(`"a\"b"`), &r); err != nil {
+		t.Fatal(err)
+	}
+
+	if s, ok := r.StringValue(); !ok || s != `a"b` || r.Text() != `a"b` {
+		t.Errorf("string value = %q, %v", s, ok)
+	}
+
+	if err := json.Unmarshal([]byte(` {"a": 1} `), &r); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := r.StringValue(); ok || r.Text() != `{"a": 1}` || r.IsNull() {
+		t.Errorf("object = %q", r)
+	}
+
+	var m map[string]int
+	if err := r.Decode(&m); err != nil || m["a"] != 1 {
+		t.Errorf("Decode = %v, %v", m, err)
+	}
+
+	if err := json.Unmarshal([]byte(`null`), &r); err != nil || !r.IsNull() {
+		t.Errorf("null = %q, %v", r, err)
+	}
+}
+
+// fixtureAPI serves the JSON files of testdata by endpoint.
+func fixtureAPI(t *testing.T, files map[string]string) *Client {
+	t.Helper()
+
+	bodies := make(map[string]string, len(files))
+
+	for endpoint, file := range files {
+		data, err := os.ReadFile(filepath.Join("testdata", file))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		bodies[endpoint] = string(data)
+	}
+
+	api := &fakeAPI{handle: func(endpoint string, _ map[string]interface{}) (int, string) {
+		body, ok := bodies[endpoint]
+		if !ok {
+			return http.StatusNotFound, `{"error": "no fixture"}`
+		}
+
+		return http.StatusOK, body
+	}}
+
+	return api.client()
+}
+
+func TestDecodeFixtures(t *testing.T) {
+	ctx := context.Background()
+	client := fixtureAPI(t, map[string]string{
+		GetProjects.endpoint: "get_projects.json",
+		GetChannels.endpoint: "get_channels.json",
+		GetMetrics.endpoint:  "get_metrics.json",
+		GetAds.endpoint:      "get_ads.json",
+	})
+
+	projects, err := client.GetProjects(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(projects) != 3 {
+		t.Fatalf("projects = %d", len(projects))
+	}
+
+	shop, all, archive := projects[0], projects[1], projects[2]
+
+	if !shop.Active() || shop.SuperObject() || shop.GroupingByObjects() ||
+		!shop.CreatedTime().Equal(time.Date(2021, 4, 12, 9, 30, 0, 0, time.UTC)) {
+		t.Errorf("shop = %+v", shop)
+	}
+
+	if city, ok := shop.Field("Город"); !ok || city != "Москва" {
+		t.Errorf("shop city = %q, %v", city, ok)
+	}
+
+	if !all.SuperObject() || !all.GroupingByObjects() || !all.CreatedTime().Equal(shop.CreatedTime()) {
+		t.Errorf("super-object = %+v", all)
+	}
+
+	if archive.Active() || !archive.CreatedTime().IsZero() || len(archive.Fields()) != 0 {
+		t.Errorf("archive = %+v", archive)
+	}
+
+	channels, err := client.GetChannels(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(channels) != 2 {
+		t.Fatalf("channels = %d", len(channels))
+	}
+
+	direct, word := channels[0], channels[1]
+
+	if !bool(direct.IsActive) || !bool(direct.IsVisible) || direct.ParentChannelID != 0 || direct.ServiceID == nil || *direct.ServiceID != 4 ||
+		direct.DataMethod() != "api" || direct.DataClass() != "YandexDirect" || direct.Deleted() || direct.CreatedAt.Hour() != 10 {
+		t.Errorf("direct = %+v", direct)
+	}
+
+	if !bool(word.IsActive) || bool(word.IsVisible) || !bool(word.IsDefaultForChannel) || word.ParentChannelID != 1 || word.ServiceID != nil ||
+		word.DataMethod() != "" || word.DataClass() != "" || strings.Join(word.ClassData.Strings(), ",") != "Manual,Import" || !strings.HasPrefix(string(word.GetDataMethod), "[") || !word.UpdatedAt.IsZero() {
+		t.Errorf("word of mouth = %+v", word)
+	}
+
+	metrics, err := client.GetMetrics(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(metrics) != 2 {
+		t.Fatalf("metrics = %d", len(metrics))
+	}
+
+	visits, cpl := metrics[0], metrics[1]
+
+	if !bool(visits.IsSystem) || bool(visits.IsGroup) || visits.MParent != 0 || visits.ServiceID != 0 || !bool(visits.EnableOriginalData) ||
+		visits.DateCreate.Year() != 2018 || visits.Formule != nil {
+		t.Errorf("visits = %+v", visits)
+	}
+
+	if bool(cpl.IsSystem) || !bool(cpl.IsGroup) || cpl.MParent != 12 || cpl.ServiceID != 3 || bool(cpl.EnableOriginalData) ||
+		!cpl.DateCreate.Equal(time.Unix(1700000000, 0)) || cpl.Formule == nil || *cpl.Formule != "{cost}/{leads}" {
+		t.Errorf("cpl = %+v", cpl)
+	}
+
+	ads, err := client.GetAds(ctx, []int{501, 502, 503})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ads) != 3 {
+		t.Fatalf("ads = %d", len(ads))
+	}
+
+	tests := []struct {
+		preview string
+		href    string
+		devices string
+	}{
+		{"https://cdn.example.com/501.png", "https://shop.example.com/?utm_source=yandex", "mobile"},
+		{"https://cdn.example.com/502-a.png", "https://shop.example.com/a", "desktop,tablet"},
+		{"", "", ""},
+	}
+
+	for i, tt := range tests {
+		ad := ads[i]
+
+		if ad.PreviewURL() != tt.preview || ad.HrefURL() != tt.href || strings.Join(ad.Devices(), ",") != tt.devices {
+			t.Errorf("ad %d: preview %q, href %q, devices %q", ad.ID, ad.PreviewURL(), ad.HrefURL(), ad.Devices())
+		}
+	}
+
+	groups := GroupCreatives([]CreativePerformance{{Ad: ads[0]}, {Ad: ads[1]}, {Ad: ads[2]}}, ByDevice)
+	if len(groups) != 3 || groups[0].Key != "" || groups[1].Key != "desktop,tablet" || groups[2].Key != "mobile" {
+		t.Errorf("groups by device = %+v", groups)
+	}
+}
+
+func TestRawJSONStrings(t *testing.T) {
+	tests := map[string]string{
+		`null`:         "",
+		`""`:           "",
+		`"a"`:          "a",
+		`[]`:           "",
+		`["a", "", 2]`: "a|2",
+		`[{"x": 1}]`:   `{"x": 1}`,
+		`{"x": 1}`:     `{"x": 1}`,
+		`3`:            "3",
+	}
+
+	for in, want := range tests {
+		var r RawJSON
+
+		err := json.Unmarshal([]byte(in), &r)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := strings.Join(r.Strings(), "|"); got != want {
+			t.Errorf("%s: got %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestAdPreviewURL(t *testing.T) {
+	tests := map[string]string{
+		`null`:                              "",
+		`"https://cdn.example.com/a.png"`:   "https://cdn.example.com/a.png",
+		`["https://cdn.example.com/b.png"]`: "https://cdn.example.com/b.png",
+		`{"url": "https://cdn.example.com/c.png", "width": 300}`: "https://cdn.example.com/c.png",
+		`{"small": "https://cdn.example.com/d.png"}`:             "",
+		`{"url": 7}`: "",
+	}
+
+	for in, want := range tests {
+		var ad Ad
+
+		err := json.Unmarshal([]byte(`{"preview_url": `+in+`}`), &ad)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := ad.PreviewURL(); got != want {
+			t.Errorf("%s: got %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package gosmartis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// KeywordColumn is the column holding the keyword ID in keyword-grouped
+// report rows.
+const KeywordColumn = "keyword_id"
+
+// KeywordPerformance is a keyword joined with its report values.
+type KeywordPerformance struct {
+	ID      int
+	Keyword string
+	// Values holds the summed measures, keyed like CreativePerformance.Values.
+	Values map[string]float64
+}
+
+// GetKeywordPerformance runs payload, which must be grouped by keyword, and
+// joins the rows with the keyword texts from GetKeywords.
+func GetKeywordPerformance(ctx context.Context, api API, payload Payload) ([]KeywordPerformance, error) {
+	reports, err := api.GetReport(ctx, payload)
+	if errors.Is(err, ErrNoReportData) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	ids, _ := sumByID(reports, KeywordColumn)
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	keywords, err := api.GetKeywords(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	return JoinKeywords(reports, keywords), nil
+}
+
+// JoinKeywords sums the measures of keyword-grouped report rows per keyword
+// and attaches the keyword texts.
+func JoinKeywords(reports []*Report, keywords []Keyword) []KeywordPerformance {
+	texts := make(map[int]string, len(keywords))
+	for _, k := range keywords {
+		texts[k.ID] = k.Keyword
+	}
+
+	ids, values := sumByID(reports, KeywordColumn)
+
+	result := make([]KeywordPerformance, 0, len(ids))
+	for _, id := range ids {
+		result = append(result, KeywordPerformance{ID: id, Keyword: texts[id], Values: values[id]})
+	}
+
+	return result
+}
+
+// KeywordWords splits a keyword into lowercase words, dropping match-type
+// operators (+word, !word, "...", [...]) and minus-words.
+func KeywordWords(keyword string) []string {
+	var words []string
+
+	for _, field := range strings.Fields(keyword) {
+		if strings.HasPrefix(field, "-") {
+			continue
+		}
+
+		words = append(words, strings.FieldsFunc(strings.ToLower(field), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})...)
+	}
+
+	return words
+}
+
+// stopWords are not counted as single-word n-grams.
+var stopWords = map[string]bool{
+	"в": true, "во": true, "на": true, "и": true, "с": true, "со": true, "по": true,
+	"для": true, "из": true, "от": true, "до": true, "за": true, "к": true, "у": true,
+	"о": true, "об": true, "а": true, "или": true, "не": true,
+	"a": true, "an": true, "the": true, "and": true, "or": true, "for": true,
+	"in": true, "on": true, "of": true, "to": true, "with": true, "by": true,
+}
+
+type NGramOptions struct {
+	// MaxN is the longest n-gram in words; defaults to 3.
+	MaxN int
+	// Cost and Leads are the Values keys used for CPL.
+	Cost  string
+	Leads string
+}
+
+// NGram aggregates the keywords containing a sequence of stemmed words.
+type NGram struct {
+	// Stem is the space-joined stems.
+	Stem string
+	// Text is the most common original spelling.
+	Text     string
+	N        int
+	Keywords int
+	Values   map[string]float64
+	// CPL is cost per lead, 0 without leads.
+	CPL float64
+}
+
+// NGrams aggregates keyword values by 1..MaxN word n-grams. Every keyword
+// counts once per n-gram it contains; prepositions and conjunctions are not
+// counted on their own. N-grams are ordered by Cost, then by stem.
+func NGrams(keywords []KeywordPerformance, opts NGramOptions) []NGram {
+	if opts.MaxN <= 0 {
+		opts.MaxN = 3
+	}
+
+	index := make(map[string]*NGram)
+	spellings := make(map[string]map[string]int)
+
+	for _, k := range keywords {
+		words := KeywordWords(k.Keyword)
+
+		stems := make([]string, len(words))
+		for i, word := range words {
+			stems[i] = Stem(word)
+		}
+
+		seen := make(map[string]bool)
+
+		for n := 1; n <= opts.MaxN; n++ {
+			for i := 0; i+n <= len(stems); i++ {
+				if n == 1 && stopWords[words[i]] {
+					continue
+				}
+
+				key := strings.Join(stems[i:i+n], " ")
+				if seen[key] {
+					continue
+				}
+
+				seen[key] = true
+
+				gram, ok := index[key]
+				if !ok {
+					gram = &NGram{Stem: key, N: n, Values: make(map[string]float64)}
+					index[key] = gram
+					spellings[key] = make(map[string]int)
+				}
+
+				gram.Keywords++
+				spellings[key][strings.Join(words[i:i+n], " ")]++
+
+				for name, value := range k.Values {
+					gram.Values[name] += value
+				}
+			}
+		}
+	}
+
+	result := make([]NGram, 0, len(index))
+
+	for key, gram := range index {
+		gram.Text = mostCommon(spellings[key])
+		gram.CPL = cpl(gram.Values, opts)
+		result = append(result, *gram)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		ci, cj := result[i].Values[opts.Cost], result[j].Values[opts.Cost]
+		if ci != cj {
+			return ci > cj
+		}
+
+		return result[i].Stem < result[j].Stem
+	})
+
+	return result
+}
+
+func cpl(values map[string]float64, opts NGramOptions) float64 {
+	if opts.Cost == "" || opts.Leads == "" || values[opts.Leads] == 0 {
+		return 0
+	}
+
+	return values[opts.Cost] / values[opts.Leads]
+}
+
+func mostCommon(counts map[string]int) string {
+	best, bestCount := "", 0
+
+	for text, count := range counts {
+		if count > bestCount || (count == bestCount && text < best) {
+			best, bestCount = text, count
+		}
+	}
+
+	return best
+}
+
+type NegativeOptions struct {
+	NGramOptions
+	// MinCost is the spend from which an n-gram without leads is flagged.
+	MinCost float64
+	// CPLFactor flags n-grams whose CPL exceeds the overall CPL this many
+	// times; zero disables the check.
+	CPLFactor float64
+}
+
+// NegativeCandidate is an n-gram worth adding as a negative keyword.
+type NegativeCandidate struct {
+	NGram
+	Reason string
+}
+
+// NegativeCandidates flags n-grams that spent at least MinCost without
+// leads, or whose CPL is CPLFactor times the CPL of all keywords.
+func NegativeCandidates(keywords []KeywordPerformance, opts NegativeOptions) []NegativeCandidate {
+	total := make(map[string]float64)
+
+	for _, k := range keywords {
+		for name, value := range k.Values {
+			total[name] += value
+		}
+	}
+
+	overall := cpl(total, opts.NGramOptions)
+
+	var result []NegativeCandidate
+
+	for _, gram := range NGrams(keywords, opts.NGramOptions) {
+		cost := gram.Values[opts.Cost]
+
+		switch {
+		case cost < opts.MinCost || cost == 0:
+		case gram.Values[opts.Leads] == 0:
+			result = append(result, NegativeCandidate{
+				NGram:  gram,
+				Reason: fmt.Sprintf("spent %s without leads", formatFloat(cost)),
+			})
+		case opts.CPLFactor > 0 && overall > 0 && gram.CPL > overall*opts.CPLFactor:
+			result = append(result, NegativeCandidate{
+				NGram:  gram,
+				Reason: fmt.Sprintf("CPL %.2f is %.1fx the average %.2f", gram.CPL, gram.CPL/overall, overall),
+			})
+		}
+	}
+
+	return result
+}
+
+// NGramReport turns n-grams into a report for the report exporters.
+func NGramReport(grams []NGram) *Report {
+	report := &Report{Metric: "ngrams", RowsMassive: make([]Row, 0, len(grams))}
+
+	for _, gram := range grams {
+		row := Row{
+			NewCell("ngram", gram.Text, ""),
+			NewCell("stem", gram.Stem, ""),
+			NewCell("n", gram.N, ""),
+			NewCell("keywords", gram.Keywords, ""),
+			NewCell("cpl", gram.CPL, ""),
+		}
+
+		for _, key := range sortedValueKeys(gram.Values) {
+			row = append(row, NewCell(key, gram.Values[key], ""))
+		}
+
+		report.RowsMassive = append(report.RowsMassive, row)
+	}
+
+	return report
+}
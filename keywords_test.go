@@ -0,0 +1,97 @@
+package gosmartis
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKeywordWords(t *testing.T) {
+	got := KeywordWords(`+купить "кожаный диван" !недорого -бу [Москва] сумка-тележка`)
+	want := "купить кожаный диван недорого москва сумка тележка"
+
+	if strings.Join(got, " ") != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func keywordFixture() []KeywordPerformance {
+	return []KeywordPerformance{
+		{ID: 1, Keyword: "купить диван", Values: map[string]float64{"cost": 100, "leads": 2}},
+		{ID: 2, Keyword: "купить диваны недорого", Values: map[string]float64{"cost": 50}},
+		{ID: 3, Keyword: "диван в москве", Values: map[string]float64{"cost": 30, "leads": 1}},
+	}
+}
+
+func TestNGrams(t *testing.T) {
+	grams := NGrams(keywordFixture(), NGramOptions{MaxN: 2, Cost: "cost", Leads: "leads"})
+
+	stems := make([]string, 0, len(grams))
+	for _, gram := range grams {
+		stems = append(stems, gram.Stem)
+	}
+
+	want := "диван|куп|куп диван|диван недор|недор|в москв|диван в|москв"
+	if got := strings.Join(stems, "|"); got != want {
+		t.Fatalf("stems = %s, want %s", got, want)
+	}
+
+	sofa := grams[0]
+	if sofa.N != 1 || sofa.Keywords != 3 || sofa.Values["cost"] != 180 || sofa.CPL != 60 || sofa.Text != "диван" {
+		t.Errorf("диван = %+v", sofa)
+	}
+
+	buySofa := grams[2]
+	if buySofa.N != 2 || buySofa.Keywords != 2 || buySofa.Text != "купить диван" || buySofa.CPL != 75 {
+		t.Errorf("куп диван = %+v", buySofa)
+	}
+
+	if grams[3].CPL != 0 {
+		t.Errorf("CPL without leads = %v", grams[3].CPL)
+	}
+
+	for _, gram := range grams {
+		if gram.Stem == "в" {
+			t.Error("stop word counted on its own")
+		}
+	}
+}
+
+func TestNGramsRepeatedWord(t *testing.T) {
+	grams := NGrams([]KeywordPerformance{{Keyword: "диван диван", Values: map[string]float64{"cost": 1}}}, NGramOptions{})
+
+	if len(grams) != 2 || grams[0].Keywords != 1 || grams[0].Values["cost"] != 1 {
+		t.Errorf("grams = %+v, want each n-gram counted once per keyword", grams)
+	}
+}
+
+func TestNegativeCandidates(t *testing.T) {
+	opts := NegativeOptions{
+		NGramOptions: NGramOptions{MaxN: 2, Cost: "cost", Leads: "leads"},
+		MinCost:      40,
+		CPLFactor:    1.2,
+	}
+
+	candidates := NegativeCandidates(keywordFixture(), opts)
+
+	got := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		got = append(got, c.Stem+": "+c.Reason)
+	}
+
+	want := []string{
+		"куп: CPL 75.00 is 1.2x the average 60.00",
+		"куп диван: CPL 75.00 is 1.2x the average 60.00",
+		"диван недор: spent 50 without leads",
+		"недор: spent 50 without leads",
+	}
+
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	opts.CPLFactor = 0
+
+	if n := len(NegativeCandidates(keywordFixture(), opts)); n != 2 {
+		t.Errorf("candidates without CPL check = %d, want 2", n)
+	}
+}
@@ -0,0 +1,444 @@
+package gosmartis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// UTM parameter names.
+const (
+	UTMSource   = "utm_source"
+	UTMMedium   = "utm_medium"
+	UTMCampaign = "utm_campaign"
+	UTMContent  = "utm_content"
+	UTMTerm     = "utm_term"
+)
+
+// UTM holds the utm_* parameters of a link.
+type UTM struct {
+	Source   string `json:"utm_source,omitempty"`
+	Medium   string `json:"utm_medium,omitempty"`
+	Campaign string `json:"utm_campaign,omitempty"`
+	Content  string `json:"utm_content,omitempty"`
+	Term     string `json:"utm_term,omitempty"`
+}
+
+// Get returns the parameter with the given name.
+func (u UTM) Get(name string) string {
+	switch name {
+	case UTMSource:
+		return u.Source
+	case UTMMedium:
+		return u.Medium
+	case UTMCampaign:
+		return u.Campaign
+	case UTMContent:
+		return u.Content
+	case UTMTerm:
+		return u.Term
+	default:
+		return ""
+	}
+}
+
+// Landing is a parsed ad link.
+type Landing struct {
+	URL *url.URL
+	UTM UTM
+	// Params holds the query parameters other than utm_*.
+	Params url.Values
+}
+
+// ParseLanding parses an absolute http or https link.
+func ParseLanding(href string) (Landing, error) {
+	href = strings.TrimSpace(href)
+	if href == "" {
+		return Landing{}, errors.New("landing url is empty")
+	}
+
+	u, err := url.Parse(href)
+	if err != nil {
+		return Landing{}, err
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return Landing{}, fmt.Errorf("landing url is not an absolute http link: %s", href)
+	}
+
+	landing := Landing{URL: u, Params: url.Values{}}
+
+	for name, values := range u.Query() {
+		if !strings.HasPrefix(name, "utm_") {
+			landing.Params[name] = values
+		}
+	}
+
+	query := u.Query()
+	landing.UTM = UTM{
+		Source:   query.Get(UTMSource),
+		Medium:   query.Get(UTMMedium),
+		Campaign: query.Get(UTMCampaign),
+		Content:  query.Get(UTMContent),
+		Term:     query.Get(UTMTerm),
+	}
+
+	return landing, nil
+}
+
+// Page returns the link without utm_* parameters and fragment.
+func (l Landing) Page() string {
+	if l.URL == nil {
+		return ""
+	}
+
+	u := *l.URL
+	u.RawQuery = l.Params.Encode()
+	u.Fragment = ""
+
+	return u.String()
+}
+
+// ParseUTM reads the utm_* query parameters of href.
+func ParseUTM(href string) (UTM, error) {
+	landing, err := ParseLanding(href)
+
+	return landing.UTM, err
+}
+
+// Landing parses the ad link, see HrefURL.
+func (a Ad) Landing() (Landing, error) {
+	return ParseLanding(a.HrefURL())
+}
+
+// UTM returns the UTM parameters of the ad link; a malformed link has none.
+func (a Ad) UTM() UTM {
+	landing, _ := a.Landing()
+
+	return landing.UTM
+}
+
+// landingKey identifies the page an ad leads to, ignoring UTM parameters.
+func landingKey(ad Ad) string {
+	landing, err := ad.Landing()
+	if err != nil {
+		return ad.HrefURL()
+	}
+
+	return landing.Page()
+}
+
+// AdContext is an ad with what UTM rules check it against. Placement and
+// Campaign are nil when unknown.
+type AdContext struct {
+	Ad           Ad
+	Landing      Landing
+	LandingError error
+	Placement    *Placement
+	Campaign     *Campaign
+}
+
+// UTMIssue is a rule violation of an ad.
+type UTMIssue struct {
+	AdID    int    `json:"ad_id"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Got     string `json:"got,omitempty"`
+	Want    string `json:"want,omitempty"`
+	Message string `json:"message"`
+}
+
+// UTMRule checks an ad and returns its issues. AdID and Rule of the issues
+// are filled in by UTMValidator.
+type UTMRule struct {
+	Name  string
+	Check func(ac AdContext) []UTMIssue
+}
+
+// DefaultUTMRules require a valid landing with source, medium, campaign
+// and content, a source matching the placement and a campaign matching the
+// ad's campaign.
+func DefaultUTMRules() []UTMRule {
+	return []UTMRule{
+		ValidLanding(),
+		RequireUTM(UTMSource, UTMMedium, UTMCampaign, UTMContent),
+		UTMMatchesPlacement(UTMSource),
+		UTMMatchesCampaign(UTMCampaign),
+	}
+}
+
+// ValidLanding requires the ad link to be an absolute http link.
+func ValidLanding() UTMRule {
+	return UTMRule{Name: "valid_landing", Check: func(ac AdContext) []UTMIssue {
+		if ac.LandingError == nil {
+			return nil
+		}
+
+		return []UTMIssue{{Got: ac.Ad.HrefURL(), Message: ac.LandingError.Error()}}
+	}}
+}
+
+// RequireUTM requires the given parameters to be set.
+func RequireUTM(params ...string) UTMRule {
+	return UTMRule{Name: "require_utm", Check: func(ac AdContext) []UTMIssue {
+		if ac.LandingError != nil {
+			return nil
+		}
+
+		var issues []UTMIssue
+
+		for _, param := range params {
+			if ac.Landing.UTM.Get(param) == "" {
+				issues = append(issues, UTMIssue{Param: param, Message: param + " is missing"})
+			}
+		}
+
+		return issues
+	}}
+}
+
+// UTMMatchesPlacement requires param to match the title or name of the
+// ad's placement; see utmMatches.
+func UTMMatchesPlacement(param string) UTMRule {
+	return UTMRule{Name: "placement", Check: func(ac AdContext) []UTMIssue {
+		value := ac.Landing.UTM.Get(param)
+		if value == "" || ac.Placement == nil {
+			return nil
+		}
+
+		candidates := []string{ac.Placement.Title}
+		if ac.Placement.Name != nil {
+			candidates = append(candidates, *ac.Placement.Name)
+		}
+
+		if utmMatches(value, candidates, nil) {
+			return nil
+		}
+
+		return []UTMIssue{{
+			Param:   param,
+			Got:     value,
+			Want:    ac.Placement.Title,
+			Message: fmt.Sprintf("%s does not match placement %q", param, ac.Placement.Title),
+		}}
+	}}
+}
+
+// UTMMatchesCampaign requires param to match the title or ID of the ad's
+// campaign, or the ad's external campaign ID. IDs must match exactly; see
+// utmMatches.
+func UTMMatchesCampaign(param string) UTMRule {
+	return UTMRule{Name: "campaign", Check: func(ac AdContext) []UTMIssue {
+		value := ac.Landing.UTM.Get(param)
+		if value == "" || ac.Campaign == nil {
+			return nil
+		}
+
+		ids := []string{ac.Ad.ExternalCampaignID}
+		if ac.Campaign.Id != 0 {
+			ids = append(ids, strconv.Itoa(ac.Campaign.Id))
+		}
+
+		if utmMatches(value, []string{ac.Campaign.Title}, ids) {
+			return nil
+		}
+
+		return []UTMIssue{{
+			Param:   param,
+			Got:     value,
+			Want:    ac.Campaign.Title,
+			Message: fmt.Sprintf("%s does not match campaign %q", param, ac.Campaign.Title),
+		}}
+	}}
+}
+
+// minUTMContainsLen is the shortest normalized title a UTM value may
+// contain instead of equal, so that short titles do not match by chance.
+const minUTMContainsLen = 3
+
+// utmMatches compares a UTM value with titles and ids ignoring case and
+// punctuation. IDs must equal the value. A title matches when it equals the
+// value or, if it is at least minUTMContainsLen runes long, is contained in
+// it, so "yandex_direct_search" matches the title "Yandex Direct" while
+// "summer2012sale" does not match the ID 12.
+func utmMatches(value string, titles, ids []string) bool {
+	v := utmNormalize(value)
+	if v == "" {
+		return false
+	}
+
+	for _, id := range ids {
+		if c := utmNormalize(id); c != "" && c == v {
+			return true
+		}
+	}
+
+	for _, title := range titles {
+		c := utmNormalize(title)
+		if c == "" {
+			continue
+		}
+
+		if c == v || (utf8.RuneCountInString(c) >= minUTMContainsLen && strings.Contains(v, c)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func utmNormalize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+
+		return -1
+	}, s)
+}
+
+// UTMValidator checks ads against rules, resolving their placements and
+// campaigns.
+type UTMValidator struct {
+	Rules      []UTMRule
+	placements map[int]Placement
+	campaigns  map[int]Campaign
+}
+
+// NewUTMValidator returns a validator for the given dictionaries. Without
+// rules DefaultUTMRules are used.
+func NewUTMValidator(placements []Placement, campaigns []Campaign, rules ...UTMRule) *UTMValidator {
+	if len(rules) == 0 {
+		rules = DefaultUTMRules()
+	}
+
+	v := &UTMValidator{
+		Rules:      rules,
+		placements: make(map[int]Placement, len(placements)),
+		campaigns:  make(map[int]Campaign, len(campaigns)),
+	}
+
+	for _, p := range placements {
+		v.placements[p.ID] = p
+	}
+
+	for _, c := range campaigns {
+		v.campaigns[c.Id] = c
+	}
+
+	return v
+}
+
+// UTMReport lists the issues of checked ads.
+type UTMReport struct {
+	Checked int        `json:"checked"`
+	Issues  []UTMIssue `json:"issues"`
+}
+
+// Misconfigured returns the IDs of ads with issues.
+func (r *UTMReport) Misconfigured() []int {
+	seen := make(map[int]bool)
+
+	var ids []int
+
+	for _, issue := range r.Issues {
+		if !seen[issue.AdID] {
+			seen[issue.AdID] = true
+			ids = append(ids, issue.AdID)
+		}
+	}
+
+	sort.Ints(ids)
+
+	return ids
+}
+
+// Report turns the issues into a report for the report exporters.
+func (r *UTMReport) Report() *Report {
+	report := &Report{Metric: "utm_issues", RowsMassive: make([]Row, 0, len(r.Issues))}
+
+	for _, issue := range r.Issues {
+		report.RowsMassive = append(report.RowsMassive, Row{
+			NewCell(string(GroupByAd), issue.AdID, ""),
+			NewCell("rule", issue.Rule, ""),
+			NewCell("param", issue.Param, ""),
+			NewCell("got", issue.Got, ""),
+			NewCell("want", issue.Want, ""),
+			NewCell("message", issue.Message, ""),
+		})
+	}
+
+	return report
+}
+
+// Validate checks every ad with every rule.
+func (v *UTMValidator) Validate(ads []Ad) *UTMReport {
+	report := &UTMReport{Checked: len(ads)}
+
+	for _, ad := range ads {
+		ac := AdContext{Ad: ad}
+		ac.Landing, ac.LandingError = ad.Landing()
+
+		if p, ok := v.placements[ad.PlacementID]; ok {
+			ac.Placement = &p
+		}
+
+		if c, ok := v.campaigns[ad.CampaignID]; ok {
+			ac.Campaign = &c
+		}
+
+		for _, rule := range v.Rules {
+			for _, issue := range rule.Check(ac) {
+				issue.AdID = ad.ID
+				issue.Rule = rule.Name
+				report.Issues = append(report.Issues, issue)
+			}
+		}
+	}
+
+	return report
+}
+
+// CheckAdUTMs fetches the ads with the given IDs together with placements
+// and campaigns and validates them.
+func CheckAdUTMs(ctx context.Context, api API, ids []int, rules ...UTMRule) (*UTMReport, error) {
+	ads, err := api.GetAds(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	placements, err := api.GetPlacements(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[int]bool)
+
+	var campaignIDs []int
+
+	for _, ad := range ads {
+		if ad.CampaignID != 0 && !seen[ad.CampaignID] {
+			seen[ad.CampaignID] = true
+			campaignIDs = append(campaignIDs, ad.CampaignID)
+		}
+	}
+
+	var campaigns []Campaign
+
+	if len(campaignIDs) > 0 {
+		sort.Ints(campaignIDs)
+
+		campaigns, err = api.GetCampaigns(ctx, campaignIDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return NewUTMValidator(placements, campaigns, rules...).Validate(ads), nil
+}
@@ -0,0 +1,84 @@
+package gosmartis
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestUTMMatches(t *testing.T) {
+	tests := []struct {
+		name   string
+		value  string
+		titles []string
+		ids    []string
+		want   bool
+	}{
+		{"equal title", "Summer Sale", []string{"summer-sale"}, nil, true},
+		{"value contains title", "yandex_direct_search", []string{"Yandex Direct"}, nil, true},
+		{"title contains value", "sale", []string{"Summer Sale"}, nil, false},
+		{"one letter source", "y", []string{"Yandex Direct"}, nil, false},
+		{"short title equal", "vk", []string{"VK"}, nil, true},
+		{"short title contained", "vkontakte", []string{"vk"}, nil, false},
+		{"id equal", "12", nil, []string{"12"}, true},
+		{"id inside value", "summer2012sale", []string{"Winter"}, []string{"12"}, false},
+		{"value inside id", "12", nil, []string{"1234"}, false},
+		{"external id", "ext-987", nil, []string{"EXT987"}, true},
+		{"empty value", "", []string{"x"}, []string{""}, false},
+		{"empty candidates", "abc", []string{""}, []string{""}, false},
+		{"cyrillic", "Летняя_распродажа_2024", []string{"Летняя распродажа"}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := utmMatches(tt.value, tt.titles, tt.ids); got != tt.want {
+				t.Errorf("utmMatches(%q, %q, %q) = %v, want %v", tt.value, tt.titles, tt.ids, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUTMValidator(t *testing.T) {
+	name := "yandex"
+	placements := []Placement{{ID: 3, Title: "Яндекс.Директ", Name: &name}}
+	campaigns := []Campaign{{Id: 12, PlacementId: 3, Title: "Brand"}}
+
+	ad := func(id int, href string) Ad {
+		return Ad{ID: id, PlacementID: 3, CampaignID: 12, ExternalCampaignID: "987", Href: RawJSON(strconv.Quote(href))}
+	}
+
+	ads := []Ad{
+		ad(1, "https://shop.example.com/?utm_source=yandex&utm_medium=cpc&utm_campaign=brand&utm_content=1"),
+		ad(2, "https://shop.example.com/?utm_source=yandex&utm_medium=cpc&utm_campaign=987&utm_content=1"),
+		ad(3, "https://shop.example.com/?utm_source=y&utm_medium=cpc&utm_campaign=summer2012sale&utm_content=1"),
+		ad(4, "https://shop.example.com/?utm_source=yandex&utm_medium=cpc"),
+		ad(5, "/relative"),
+	}
+
+	report := NewUTMValidator(placements, campaigns).Validate(ads)
+
+	if report.Checked != 5 {
+		t.Errorf("checked = %d", report.Checked)
+	}
+
+	got := make(map[int][]string)
+	for _, issue := range report.Issues {
+		got[issue.AdID] = append(got[issue.AdID], issue.Rule+":"+issue.Param)
+	}
+
+	want := map[int]string{
+		3: "placement:utm_source campaign:utm_campaign",
+		4: "require_utm:utm_campaign require_utm:utm_content",
+		5: "valid_landing:",
+	}
+
+	for id := 1; id <= 5; id++ {
+		if g := strings.Join(got[id], " "); g != want[id] {
+			t.Errorf("ad %d issues = %q, want %q", id, g, want[id])
+		}
+	}
+
+	if ids := report.Misconfigured(); len(ids) != 3 || ids[0] != 3 || ids[2] != 5 {
+		t.Errorf("misconfigured = %v", ids)
+	}
+}
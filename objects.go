@@ -0,0 +1,169 @@
+package gosmartis
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// ObjectColumn holds the sub-object of rows grouped by GroupByObject, and
+// ObjectTitleColumn the title ResolveObjects finds for it.
+const (
+	ObjectColumn      = string(GroupByObject)
+	ObjectTitleColumn = "smartis_object_title"
+)
+
+// CheckObjectGrouping returns ErrObjectGroupingNotAllowed if p cannot be
+// grouped by GroupByObject.
+func CheckObjectGrouping(p Project) error {
+	if !p.GroupingByObjects() {
+		return fmt.Errorf("%w: %s", ErrObjectGroupingNotAllowed, p.Project)
+	}
+
+	return nil
+}
+
+// GetReportByObjects runs payload grouped by GroupByObject after checking
+// that the project allows it, and resolves the sub-objects to project
+// titles.
+func GetReportByObjects(ctx context.Context, api API, payload Payload) ([]*Report, error) {
+	reports, _, err := getReportByObjects(ctx, api, payload, CheckObjectGrouping)
+
+	return reports, err
+}
+
+// getReportByObjects runs payload grouped by GroupByObject once check
+// accepts the payload's project.
+func getReportByObjects(ctx context.Context, api API, payload Payload, check func(Project) error) ([]*Report, []Project, error) {
+	projects, err := api.GetProjects(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	project, ok := FindProject(projects, payload.Project)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %s", ErrProjectNotFound, payload.Project)
+	}
+
+	err = check(project)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	payload.GroupBy = GroupByObject
+
+	reports, err := api.GetReport(ctx, payload)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ResolveObjects(reports, projects)
+
+	return reports, projects, nil
+}
+
+// SubObjects lists the projects a super-object project consists of. The
+// API has no direct call for it, so they are taken from a report of
+// payload grouped by GroupByObject; sub-objects without data in the
+// payload's date range are not listed. Sub-objects that are not among the
+// account's projects are returned with only Project set.
+// ErrObjectGroupingNotAllowed is returned before any report is requested
+// unless the project is a super-object that allows the grouping.
+func SubObjects(ctx context.Context, api API, payload Payload) ([]Project, error) {
+	reports, projects, err := getReportByObjects(ctx, api, payload, checkSuperObject)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+
+	var result []Project
+
+	for _, report := range reports {
+		for _, row := range report.RowsMassive {
+			value := row.Value(ObjectColumn)
+			if value == nil {
+				continue
+			}
+
+			code := objectCode(value)
+			if seen[code] {
+				continue
+			}
+
+			seen[code] = true
+
+			project, ok := matchProject(projects, value)
+			if !ok {
+				project = Project{Project: code}
+			}
+
+			result = append(result, project)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Project < result[j].Project })
+
+	return result, nil
+}
+
+// ResolveObjects adds an ObjectTitleColumn cell to every row with an
+// ObjectColumn value. Cell names are column names, so the ObjectColumn
+// cells are left as they are. Objects are matched by project code or ID.
+func ResolveObjects(reports []*Report, projects []Project) {
+	for _, report := range reports {
+		for i, row := range report.RowsMassive {
+			for _, cell := range row {
+				if cell.ColumnID != ObjectColumn || cell.Value == nil {
+					continue
+				}
+
+				project, ok := matchProject(projects, cell.Value)
+				if !ok {
+					break
+				}
+
+				if row.Value(ObjectTitleColumn) == nil {
+					report.RowsMassive[i] = append(row, NewCell(ObjectTitleColumn, project.Title, ""))
+				}
+
+				break
+			}
+		}
+	}
+}
+
+func checkSuperObject(p Project) error {
+	if !p.SuperObject() {
+		return fmt.Errorf("%w: %s is not a super-object", ErrObjectGroupingNotAllowed, p.Project)
+	}
+
+	return CheckObjectGrouping(p)
+}
+
+func matchProject(projects []Project, value interface{}) (Project, bool) {
+	code := objectCode(value)
+
+	for _, p := range projects {
+		if p.Project == code || strconv.Itoa(p.ID) == code {
+			return p, true
+		}
+	}
+
+	return Project{}, false
+}
+
+// objectCode formats an ObjectColumn value, which is a project code or a
+// numeric project ID. IDs are written in full, never in exponent form.
+func objectCode(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+
+	if f, ok := CellFloat(value); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+
+	return fmt.Sprint(value)
+}
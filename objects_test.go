@@ -0,0 +1,107 @@
+package gosmartis
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestResolveObjects(t *testing.T) {
+	projects := []Project{
+		{ID: 7, Project: "shop", Title: "Shop"},
+		{ID: 8, Project: "blog", Title: "Blog"},
+		{ID: 1000000, Project: "shop_1000000", Title: "Big shop"},
+	}
+
+	report := &Report{Metric: "leads", RowsMassive: []Row{
+		{NewCell(ObjectColumn, "shop", "Object"), NewCell("leads", 1.0, "")},
+		{NewCell(ObjectColumn, 8.0, "Object"), NewCell("leads", 2.0, "")},
+		{NewCell(ObjectColumn, "gone", "Object"), NewCell("leads", 3.0, "")},
+		{NewCell(ObjectColumn, nil, "Object"), NewCell("leads", 4.0, "")},
+		{NewCell(ObjectColumn, 1e6, "Object"), NewCell("leads", 5.0, "")},
+	}}
+
+	ResolveObjects([]*Report{report}, projects)
+	// Resolving twice adds no second title cell.
+	ResolveObjects([]*Report{report}, projects)
+
+	want := []interface{}{"Shop", "Blog", nil, nil, "Big shop"}
+
+	for i, row := range report.RowsMassive {
+		if got := row.Value(ObjectTitleColumn); got != want[i] {
+			t.Errorf("row %d title = %v, want %v", i, got, want[i])
+		}
+
+		titles := 0
+
+		for _, cell := range row {
+			if cell.ColumnID == ObjectColumn && cell.Name != "Object" {
+				t.Errorf("row %d: object cell name = %q, want the column name kept", i, cell.Name)
+			}
+
+			if cell.ColumnID == ObjectTitleColumn {
+				titles++
+			}
+		}
+
+		if titles > 1 {
+			t.Errorf("row %d has %d title cells", i, titles)
+		}
+	}
+}
+
+func TestCheckObjectGrouping(t *testing.T) {
+	err := CheckObjectGrouping(Project{Project: "shop", CanGroupingByObjects: true})
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = CheckObjectGrouping(Project{Project: "shop"})
+	if !errors.Is(err, ErrObjectGroupingNotAllowed) {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestSubObjects(t *testing.T) {
+	projects := `{"projects": [
+		{"id": 1, "project": "group", "is_super_object": 1, "can_grouping_by_objects": 1},
+		{"id": 2, "project": "plain", "can_grouping_by_objects": 1},
+		{"id": 3, "project": "flat", "is_super_object": 1},
+		{"id": 1000000, "project": "shop", "title": "Shop"}
+	]}`
+
+	api := &fakeAPI{handle: func(endpoint string, body map[string]interface{}) (int, string) {
+		if endpoint == GetProjects.endpoint {
+			return http.StatusOK, projects
+		}
+
+		return http.StatusOK, `{"reports": {"leads": [
+			{"smartis_object": 1000000, "leads": 1},
+			{"smartis_object": "1000000", "leads": 2},
+			{"smartis_object": "blog", "leads": 3}
+		]}}`
+	}}
+
+	client := api.client()
+
+	subs, err := SubObjects(context.Background(), client, Payload{Project: "group", Metrics: []string{"leads"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(subs) != 2 || subs[0].Project != "blog" || subs[0].ID != 0 || subs[1].ID != 1000000 || subs[1].Title != "Shop" {
+		t.Errorf("sub-objects = %+v", subs)
+	}
+
+	for _, project := range []string{"plain", "flat"} {
+		_, err = SubObjects(context.Background(), client, Payload{Project: project, Metrics: []string{"leads"}})
+		if !errors.Is(err, ErrObjectGroupingNotAllowed) {
+			t.Errorf("%s: err = %v", project, err)
+		}
+	}
+
+	if n := api.count(GetReports.endpoint); n != 1 {
+		t.Errorf("%d reports requested, want none for rejected projects", n)
+	}
+}
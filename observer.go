@@ -0,0 +1,101 @@
+package gosmartis
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Observer is notified of Client calls and of the HTTP requests they send,
+// for tracing and metrics; the telemetry package implements it with
+// OpenTelemetry. Client.Observer is nil by default, which costs nothing.
+type Observer interface {
+	// StartCall is called when a Client method starts. The returned context
+	// is used for the call, so its requests and nested calls see it. end is
+	// called once with the number of report rows or items decoded.
+	StartCall(ctx context.Context, call Call) (_ context.Context, end func(rows int, err error))
+	// StartRequest is called before an HTTP request is sent. end is called
+	// once, when the response body is closed or the request failed.
+	StartRequest(ctx context.Context, req Request) (_ context.Context, end func(RequestResult))
+}
+
+// Call describes a Client method call.
+type Call struct {
+	// Method is the method name, e.g. "GetReport".
+	Method string
+	// Payload is the payload of GetReport calls.
+	Payload Payload
+	// IDs is the number of IDs requested by the dictionary getters; zero
+	// requests all items.
+	IDs int
+}
+
+// Request describes an HTTP request to the Smartis API.
+type Request struct {
+	// Endpoint is the API path, e.g. "reports/getReport".
+	Endpoint string
+	// Bytes is the size of the request body.
+	Bytes int
+	// Wait is the time spent in Client.Limiter before the request, zero
+	// without a Limiter.
+	Wait time.Duration
+}
+
+// RequestResult is the outcome of a Request.
+type RequestResult struct {
+	// StatusCode is zero when no response was received.
+	StatusCode int
+	// Bytes is the number of response body bytes read.
+	Bytes int64
+	Err   error
+}
+
+func noEndCall(int, error) {}
+
+// startCall notifies the Observer, if any, of a call.
+func (c *Client) startCall(ctx context.Context, call Call) (context.Context, func(int, error)) {
+	if c.Observer == nil {
+		return ctx, noEndCall
+	}
+
+	return c.Observer.StartCall(ctx, call)
+}
+
+// observedBody reports the response size when the body is closed.
+type observedBody struct {
+	io.ReadCloser
+	result RequestResult
+	end    func(RequestResult)
+}
+
+func (b *observedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.result.Bytes += int64(n)
+
+	if err != nil && err != io.EOF {
+		b.result.Err = err
+	}
+
+	return n, err
+}
+
+func (b *observedBody) Close() error {
+	err := b.ReadCloser.Close()
+
+	if b.end != nil {
+		b.end(b.result)
+		b.end = nil
+	}
+
+	return err
+}
+
+// countRows walks every report, so callers only count rows for an Observer.
+func countRows(reports []*Report) int {
+	rows := 0
+	for _, r := range reports {
+		rows += len(r.RowsMassive)
+	}
+
+	return rows
+}
@@ -0,0 +1,281 @@
+package gosmartis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// projectsTTL is how long GetProject serves projects from its cache.
+const projectsTTL = 10 * time.Minute
+
+// SuperObject reports whether the project aggregates other projects.
+func (p Project) SuperObject() bool {
+	return bool(p.IsSuperObject)
+}
+
+// GroupingByObjects reports whether reports of the project may be grouped
+// by GroupByObject.
+func (p Project) GroupingByObjects() bool {
+	return bool(p.CanGroupingByObjects)
+}
+
+// FindProject returns the project with the given code.
+func FindProject(projects []Project, code string) (Project, bool) {
+	for _, p := range projects {
+		if p.Project == code {
+			return p, true
+		}
+	}
+
+	return Project{}, false
+}
+
+// CreatedTime returns when the project was created, or the zero time.
+func (p Project) CreatedTime() time.Time {
+	return p.CreatedAt.Time
+}
+
+// Active reports whether the project is active.
+func (p Project) Active() bool {
+	return bool(p.IsActive)
+}
+
+// Field returns the value of the project field with the given title.
+func (p Project) Field(title string) (string, bool) {
+	for _, f := range p.ProjectFields {
+		if f.Title == title {
+			return f.Value, true
+		}
+	}
+
+	return "", false
+}
+
+// Fields returns the project fields keyed by title.
+func (p Project) Fields() map[string]string {
+	fields := make(map[string]string, len(p.ProjectFields))
+	for _, f := range p.ProjectFields {
+		fields[f.Title] = f.Value
+	}
+
+	return fields
+}
+
+// GetProject returns the project with the given code. Projects are cached
+// for projectsTTL; a code missing from the cache refreshes it once, and a
+// code still missing after that is reported as not found for projectsTTL
+// without asking the API again. Concurrent refreshes share one GetProjects
+// call, which runs without holding the cache lock.
+func (c *Client) GetProject(ctx context.Context, code string) (Project, error) {
+	refreshed := false
+
+	for {
+		c.projectsMu.Lock()
+
+		if time.Since(c.projectsAt) < projectsTTL {
+			if p, ok := c.projects[code]; ok {
+				c.projectsMu.Unlock()
+
+				return p, nil
+			}
+
+			if refreshed {
+				if c.projectsMissing == nil {
+					c.projectsMissing = make(map[string]time.Time)
+				}
+
+				c.projectsMissing[code] = time.Now()
+			}
+
+			if time.Since(c.projectsMissing[code]) < projectsTTL {
+				c.projectsMu.Unlock()
+
+				return Project{}, fmt.Errorf("%w: %s", ErrProjectNotFound, code)
+			}
+		}
+
+		load := c.projectsLoad
+		if load == nil {
+			load = &projectsLoad{done: make(chan struct{})}
+			c.projectsLoad = load
+			c.projectsMu.Unlock()
+
+			c.loadProjects(ctx, load)
+		} else {
+			c.projectsMu.Unlock()
+
+			select {
+			case <-load.done:
+			case <-ctx.Done():
+				return Project{}, ctx.Err()
+			}
+
+			// The call that loaded the projects was canceled, not ours.
+			if ctx.Err() == nil && (errors.Is(load.err, context.Canceled) || errors.Is(load.err, context.DeadlineExceeded)) {
+				continue
+			}
+		}
+
+		if load.err != nil {
+			return Project{}, load.err
+		}
+
+		refreshed = true
+	}
+}
+
+// projectsLoad is a GetProjects call shared by concurrent GetProject calls.
+type projectsLoad struct {
+	done chan struct{}
+	err  error
+}
+
+func (c *Client) loadProjects(ctx context.Context, load *projectsLoad) {
+	projects, err := c.GetProjects(ctx)
+
+	c.projectsMu.Lock()
+	defer c.projectsMu.Unlock()
+
+	if err == nil {
+		c.projects = make(map[string]Project, len(projects))
+		for _, p := range projects {
+			c.projects[p.Project] = p
+		}
+
+		c.projectsAt = time.Now()
+	}
+
+	load.err = err
+	c.projectsLoad = nil
+	close(load.done)
+}
+
+// ResetProjects drops the projects cached by GetProject.
+func (c *Client) ResetProjects() {
+	c.projectsMu.Lock()
+	defer c.projectsMu.Unlock()
+
+	c.projects = nil
+	c.projectsAt = time.Time{}
+	c.projectsMissing = nil
+}
+
+// ProjectColumn is the column GetReportForProjects tags rows with.
+const ProjectColumn = "project"
+
+// ProjectError is the failure of a single project of a fan-out.
+type ProjectError struct {
+	Project string
+	Err     error
+}
+
+func (e *ProjectError) Error() string {
+	return fmt.Sprintf("project %s: %v", e.Project, e.Err)
+}
+
+func (e *ProjectError) Unwrap() error {
+	return e.Err
+}
+
+// ProjectReports is the merged result of GetReportForProjects.
+type ProjectReports struct {
+	// Reports holds one report per metric with the rows of all projects.
+	Reports []*Report
+	// Succeeded lists the projects whose reports were merged, including
+	// projects without data.
+	Succeeded []string
+	// Errors holds the projects that failed, in the order given.
+	Errors []*ProjectError
+}
+
+// Err returns the joined project errors, or nil.
+func (r *ProjectReports) Err() error {
+	errs := make([]error, 0, len(r.Errors))
+	for _, err := range r.Errors {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// GetReportForProjects runs payload for every project concurrently, within
+// Client.Limiter, and merges the reports by metric. Every row gets a
+// ProjectColumn cell holding its project code. A failing project does not
+// fail the batch; see ProjectReports.Errors.
+func (c *Client) GetReportForProjects(ctx context.Context, payload Payload, projects []string) (batch *ProjectReports, err error) {
+	ctx, end := c.startCall(ctx, Call{Method: "GetReportForProjects", Payload: payload})
+	defer func() {
+		if c.Observer == nil {
+			return
+		}
+
+		rows := 0
+		if batch != nil {
+			rows = countRows(batch.Reports)
+		}
+
+		end(rows, err)
+	}()
+
+	if len(projects) == 0 {
+		return nil, errors.New("no projects to query")
+	}
+
+	results := make([][]*Report, len(projects))
+
+	errs := forEach(ctx, len(projects), func(ctx context.Context, i int) error {
+		p := payload
+		p.Project = projects[i]
+
+		reports, err := c.GetReport(ctx, p)
+		if errors.Is(err, ErrNoReportData) {
+			return nil
+		}
+
+		results[i] = reports
+
+		return err
+	})
+
+	err = ctx.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ProjectReports{}
+	byMetric := make(map[string]*Report)
+
+	for i, project := range projects {
+		if errs[i] != nil {
+			result.Errors = append(result.Errors, &ProjectError{Project: project, Err: errs[i]})
+
+			continue
+		}
+
+		result.Succeeded = append(result.Succeeded, project)
+
+		for _, report := range results[i] {
+			merged, ok := byMetric[report.Metric]
+			if !ok {
+				merged = &Report{Metric: report.Metric}
+				byMetric[report.Metric] = merged
+				result.Reports = append(result.Reports, merged)
+			}
+
+			for _, row := range report.RowsMassive {
+				tagged := make(Row, 0, len(row)+1)
+				tagged = append(tagged, NewCell(ProjectColumn, project, ""))
+				tagged = append(tagged, row...)
+
+				merged.RowsMassive = append(merged.RowsMassive, tagged)
+			}
+		}
+	}
+
+	sort.Slice(result.Reports, func(i, j int) bool { return result.Reports[i].Metric < result.Reports[j].Metric })
+
+	return result, nil
+}
@@ -0,0 +1,271 @@
+package gosmartis
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestGetReportForProjects(t *testing.T) {
+	api := &fakeAPI{handle: func(endpoint string, body map[string]interface{}) (int, string) {
+		switch body["project"] {
+		case "a":
+			return http.StatusOK, `{"reports": {"leads": [{"day": "2024-01-01", "leads": 1}], "visits": [{"day": "2024-01-01", "visits": 5}]}}`
+		case "b":
+			return http.StatusOK, `{"reports": {"leads": [{"day": "2024-01-01", "leads": 2}, {"day": "2024-01-02", "leads": 3}]}}`
+		case "empty":
+			return http.StatusOK, `{"reports": {}}`
+		case "broken":
+			return http.StatusBadRequest, `{"error": "bad project", "code": 400}`
+		default:
+			return http.StatusInternalServerError, ``
+		}
+	}}
+
+	payload := Payload{Metrics: []string{"leads", "visits"}, GroupBy: GroupByDay}
+
+	batch, err := api.client().GetReportForProjects(context.Background(), payload, []string{"a", "broken", "b", "empty", "down"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(batch.Succeeded, ",") != "a,b,empty" {
+		t.Errorf("succeeded = %v", batch.Succeeded)
+	}
+
+	if len(batch.Errors) != 2 || batch.Errors[0].Project != "broken" || batch.Errors[1].Project != "down" {
+		t.Fatalf("errors = %v", batch.Errors)
+	}
+
+	var apiErr *APIError
+	if !errors.As(batch.Errors[0], &apiErr) {
+		t.Errorf("project error does not unwrap to APIError: %v", batch.Errors[0])
+	}
+
+	err = batch.Err()
+	if !errors.Is(err, errInternalError) || !strings.Contains(err.Error(), "project down:") {
+		t.Errorf("Err = %v", err)
+	}
+
+	if len(batch.Reports) != 2 || batch.Reports[0].Metric != "leads" || batch.Reports[1].Metric != "visits" {
+		t.Fatalf("reports = %+v", batch.Reports)
+	}
+
+	leads := batch.Reports[0]
+	if len(leads.RowsMassive) != 3 {
+		t.Fatalf("lead rows = %d, want 3", len(leads.RowsMassive))
+	}
+
+	projects := map[string]float64{}
+	for _, row := range leads.RowsMassive {
+		n, _ := CellFloat(row.Value("leads"))
+		projects[row.Value(ProjectColumn).(string)] += n
+	}
+
+	if projects["a"] != 1 || projects["b"] != 5 {
+		t.Errorf("leads by project = %v", projects)
+	}
+
+	if row := batch.Reports[1].RowsMassive[0]; row.Value(ProjectColumn) != "a" {
+		t.Errorf("visits row project = %v", row.Value(ProjectColumn))
+	}
+}
+
+func TestGetReportForProjectsAllSucceed(t *testing.T) {
+	api := &fakeAPI{handle: func(string, map[string]interface{}) (int, string) {
+		return http.StatusOK, `{"reports": {"leads": [{"day": "2024-01-01", "leads": 1}]}}`
+	}}
+
+	batch, err := api.client().GetReportForProjects(context.Background(), Payload{}, []string{"a", "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if batch.Err() != nil || len(batch.Errors) != 0 {
+		t.Errorf("Err = %v", batch.Err())
+	}
+
+	if api.count(GetReports.endpoint) != 2 {
+		t.Errorf("requests = %d, want 2", api.count(GetReports.endpoint))
+	}
+
+	_, err = api.client().GetReportForProjects(context.Background(), Payload{}, nil)
+	if err == nil {
+		t.Error("no projects is not an error")
+	}
+}
+
+func TestGetReportForProjectsCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	api := &fakeAPI{handle: func(string, map[string]interface{}) (int, string) {
+		return http.StatusOK, `{"reports": {}}`
+	}}
+
+	_, err := api.client().GetReportForProjects(ctx, Payload{}, []string{"a"})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
+
+func TestGetProjectCache(t *testing.T) {
+	api := &fakeAPI{handle: func(string, map[string]interface{}) (int, string) {
+		return http.StatusOK, `{"projects": [{"id": 1, "project": "shop", "title": "Shop"}]}`
+	}}
+	client := api.client()
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		p, err := client.GetProject(ctx, "shop")
+		if err != nil || p.Title != "Shop" {
+			t.Fatalf("GetProject = %+v, %v", p, err)
+		}
+	}
+
+	if n := api.count(GetProjects.endpoint); n != 1 {
+		t.Errorf("GetProjects calls after hits = %d, want 1", n)
+	}
+
+	// A miss refreshes once; repeated misses are answered from the cache.
+	for i := 0; i < 3; i++ {
+		_, err := client.GetProject(ctx, "blog")
+		if !errors.Is(err, ErrProjectNotFound) {
+			t.Fatalf("missing project: %v", err)
+		}
+	}
+
+	if n := api.count(GetProjects.endpoint); n != 2 {
+		t.Errorf("GetProjects calls after misses = %d, want 2", n)
+	}
+
+	client.ResetProjects()
+
+	_, err := client.GetProject(ctx, "blog")
+	if !errors.Is(err, ErrProjectNotFound) {
+		t.Fatalf("missing project after reset: %v", err)
+	}
+
+	if n := api.count(GetProjects.endpoint); n != 3 {
+		t.Errorf("GetProjects calls after reset = %d, want 3", n)
+	}
+}
+
+func TestGetProjectError(t *testing.T) {
+	status := http.StatusInternalServerError
+	api := &fakeAPI{handle: func(string, map[string]interface{}) (int, string) {
+		return status, `{"projects": [{"id": 1, "project": "shop"}]}`
+	}}
+	client := api.client()
+
+	_, err := client.GetProject(context.Background(), "shop")
+	if !errors.Is(err, errInternalError) {
+		t.Fatalf("err = %v", err)
+	}
+
+	// Failures are not cached.
+	status = http.StatusOK
+
+	_, err = client.GetProject(context.Background(), "shop")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGetProjectSharesLoad(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	var once sync.Once
+
+	api := &fakeAPI{handle: func(string, map[string]interface{}) (int, string) {
+		once.Do(func() { close(started) })
+		<-release
+
+		return http.StatusOK, `{"projects": [{"id": 1, "project": "shop"}]}`
+	}}
+	client := api.client()
+
+	var wg sync.WaitGroup
+
+	errs := make(chan error, 10)
+
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_, err := client.GetProject(context.Background(), "shop")
+			errs <- err
+		}()
+	}
+
+	<-started
+
+	// The cache lock is not held during the fetch, so a caller that gives
+	// up returns right away.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := client.GetProject(ctx, "shop")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("canceled caller: %v", err)
+	}
+
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+
+	if n := api.count(GetProjects.endpoint); n != 1 {
+		t.Errorf("GetProjects calls = %d, want 1", n)
+	}
+}
+
+func TestFanOutsShareLimiter(t *testing.T) {
+	api := &fakeAPI{handle: func(string, map[string]interface{}) (int, string) {
+		return http.StatusOK, `{"reports": {"leads": [{"day": "2024-01-01", "leads": 1}]}}`
+	}}
+
+	limiter := &countingLimiter{}
+	client := api.client()
+	client.Limiter = limiter
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+
+		_, err := client.CompareAttributions(ctx, Payload{Project: "a"}, 1, 2, 3)
+		if err != nil {
+			t.Error(err)
+		}
+	}()
+
+	go func() {
+		defer wg.Done()
+
+		_, err := client.GetReportForProjects(ctx, Payload{}, []string{"a", "b", "c", "d"})
+		if err != nil {
+			t.Error(err)
+		}
+	}()
+
+	wg.Wait()
+
+	if limiter.waits != 7 {
+		t.Errorf("limiter waits = %d, want 7 for both calls", limiter.waits)
+	}
+}
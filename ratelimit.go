@@ -0,0 +1,104 @@
+package gosmartis
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// maxConcurrentRequests bounds the requests a single Client call such as
+// CompareAttributions runs in parallel.
+const maxConcurrentRequests = 4
+
+// RateLimiter throttles the requests of a Client. *rate.Limiter from
+// golang.org/x/time/rate satisfies it.
+type RateLimiter interface {
+	Wait(ctx context.Context) error
+}
+
+type rateLimiter struct {
+	mu sync.Mutex
+	// step is the time one request takes from the budget.
+	step time.Duration
+	// burst is how far ahead of schedule requests may run.
+	burst time.Duration
+	// next is the time the budget is fully used up to.
+	next time.Time
+}
+
+// NewRateLimiter allows n requests per interval, of which up to n may be
+// sent at once.
+func NewRateLimiter(n int, interval time.Duration) RateLimiter {
+	if n < 1 {
+		n = 1
+	}
+
+	step := interval / time.Duration(n)
+
+	return &rateLimiter{
+		step:  step,
+		burst: step * time.Duration(n-1),
+	}
+}
+
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+
+	start := l.next.Add(-l.burst)
+	l.next = l.next.Add(l.step)
+
+	l.mu.Unlock()
+
+	delay := start.Sub(now)
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// forEach calls fn for 0..n-1 with at most maxConcurrentRequests calls
+// running at once and returns the errors by index.
+func forEach(ctx context.Context, n int, fn func(ctx context.Context, i int) error) []error {
+	errs := make([]error, n)
+	sem := make(chan struct{}, maxConcurrentRequests)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+
+				return
+			}
+
+			defer func() { <-sem }()
+
+			errs[i] = fn(ctx, i)
+		}(i)
+	}
+
+	wg.Wait()
+
+	return errs
+}
@@ -0,0 +1,200 @@
+package gosmartis
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterBurst(t *testing.T) {
+	limiter := NewRateLimiter(3, 300*time.Millisecond)
+	ctx := context.Background()
+	start := time.Now()
+
+	for i := 0; i < 3; i++ {
+		err := limiter.Wait(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("burst of 3 took %v", elapsed)
+	}
+
+	err := limiter.Wait(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("fourth request after %v, want it to wait for the budget", elapsed)
+	}
+}
+
+func TestRateLimiterCanceled(t *testing.T) {
+	limiter := NewRateLimiter(1, time.Hour)
+
+	err := limiter.Wait(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+
+	err = limiter.Wait(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("err = %v, want the context error", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("canceled wait returned after %v", elapsed)
+	}
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = limiter.Wait(canceled)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("err with a canceled context = %v", err)
+	}
+}
+
+func TestForEach(t *testing.T) {
+	const n = 25
+
+	var (
+		running, peak int32
+		mu            sync.Mutex
+		calls         = make(map[int]int)
+	)
+
+	errs := forEach(context.Background(), n, func(_ context.Context, i int) error {
+		now := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+
+		for {
+			old := atomic.LoadInt32(&peak)
+			if now <= old || atomic.CompareAndSwapInt32(&peak, old, now) {
+				break
+			}
+		}
+
+		mu.Lock()
+		calls[i]++
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		if i%5 == 0 {
+			return errors.New("failed")
+		}
+
+		return nil
+	})
+
+	if len(errs) != n || len(calls) != n {
+		t.Fatalf("%d errors and %d indices called, want %d", len(errs), len(calls), n)
+	}
+
+	for i := 0; i < n; i++ {
+		if calls[i] != 1 {
+			t.Errorf("index %d called %d times", i, calls[i])
+		}
+
+		if failed := errs[i] != nil; failed != (i%5 == 0) {
+			t.Errorf("errs[%d] = %v", i, errs[i])
+		}
+	}
+
+	if peak > maxConcurrentRequests || peak < 2 {
+		t.Errorf("peak concurrency = %d, want 2..%d", peak, maxConcurrentRequests)
+	}
+}
+
+func TestForEachCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	errs := forEach(ctx, 10, func(ctx context.Context, _ int) error {
+		return ctx.Err()
+	})
+
+	for i, err := range errs {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("errs[%d] = %v", i, err)
+		}
+	}
+}
+
+// countingLimiter counts Wait calls.
+type countingLimiter struct {
+	waits int32
+}
+
+func (l *countingLimiter) Wait(context.Context) error {
+	atomic.AddInt32(&l.waits, 1)
+
+	return nil
+}
+
+func TestCompareAttributionsUsesLimiter(t *testing.T) {
+	var running, peak int32
+
+	api := &fakeAPI{handle: func(endpoint string, body map[string]interface{}) (int, string) {
+		now := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+
+		for {
+			old := atomic.LoadInt32(&peak)
+			if now <= old || atomic.CompareAndSwapInt32(&peak, old, now) {
+				break
+			}
+		}
+
+		time.Sleep(time.Millisecond)
+
+		return http.StatusOK, `{"reports": {"leads": [{"day": "2024-01-01", "leads": 1}]}}`
+	}}
+
+	limiter := &countingLimiter{}
+	client := api.client()
+	client.Limiter = limiter
+
+	models := []AttributionModel{1, 2, 3, 4, 5, 6}
+
+	cmp, err := client.CompareAttributions(context.Background(), Payload{Project: "object_1"}, models...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(cmp.Rows) != 1 || len(cmp.Columns) != 1 || cmp.Columns[0].Totals[6] != 1 {
+		t.Errorf("comparison = %+v", cmp)
+	}
+
+	if limiter.waits != int32(len(models)) {
+		t.Errorf("limiter waits = %d, want one per model", limiter.waits)
+	}
+
+	if peak > maxConcurrentRequests {
+		t.Errorf("peak concurrent requests = %d", peak)
+	}
+
+	seen := make(map[float64]bool)
+
+	for _, req := range api.requests {
+		attribution, _ := req.body["attribution"].(map[string]interface{})
+		seen[attribution["model_id"].(float64)] = true
+	}
+
+	if len(seen) != len(models) {
+		t.Errorf("requested models = %v", seen)
+	}
+}
@@ -0,0 +1,370 @@
+// Package recorder provides an http.RoundTripper that records Smartis API
+// traffic to cassette files and replays it later without network access.
+//
+// Plug it into the HTTP client passed to gosmartis.NewClient:
+//
+//	rec, err := recorder.New("testdata/projects.json", recorder.ModeReplay, nil)
+//	if err != nil {
+//		return err
+//	}
+//	defer rec.Close()
+//
+//	client := gosmartis.NewClient(apiKey, crmToken, &http.Client{Transport: rec})
+package recorder
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Mode selects how a Recorder handles requests.
+type Mode int
+
+const (
+	// ModeReplay serves responses from the cassette and never touches the network.
+	ModeReplay Mode = iota
+	// ModeRecord sends requests to the network and appends them to the cassette.
+	ModeRecord
+	// ModePassthrough sends requests to the network without touching the cassette.
+	ModePassthrough
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModeReplay:
+		return "replay"
+	case ModeRecord:
+		return "record"
+	case ModePassthrough:
+		return "passthrough"
+	default:
+		return fmt.Sprintf("Mode(%d)", int(m))
+	}
+}
+
+const redacted = "REDACTED"
+
+// redactedBodyFields lists request body fields that never reach a cassette.
+var redactedBodyFields = []string{"smartis_crm_token"}
+
+// ErrNoInteraction is returned in replay mode when the cassette holds no
+// interaction matching the request.
+var ErrNoInteraction = errors.New("recorder: no recorded interaction matches request")
+
+// Interaction is a single recorded request and response pair.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+type RecordedRequest struct {
+	Method   string              `json:"method"`
+	Endpoint string              `json:"endpoint"`
+	Header   map[string][]string `json:"header,omitempty"`
+	Body     json.RawMessage     `json:"body,omitempty"`
+}
+
+type RecordedResponse struct {
+	StatusCode int                 `json:"status_code"`
+	Header     map[string][]string `json:"header,omitempty"`
+	Body       json.RawMessage     `json:"body,omitempty"`
+	RawBody    string              `json:"raw_body,omitempty"`
+}
+
+type cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Recorder is an http.RoundTripper backed by a cassette file.
+type Recorder struct {
+	path string
+	mode Mode
+	next http.RoundTripper
+
+	mu       sync.Mutex
+	cassette cassette
+	used     map[int]bool
+}
+
+// New creates a Recorder for the cassette at path.
+// In replay mode the cassette must exist. In record mode the cassette is
+// rewritten from scratch on the first recorded request. A nil next falls back to
+// http.DefaultTransport.
+func New(path string, mode Mode, next http.RoundTripper) (*Recorder, error) {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+
+	r := &Recorder{
+		path: path,
+		mode: mode,
+		next: next,
+		used: make(map[int]bool),
+	}
+
+	if mode == ModeReplay {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("recorder: read cassette: %w", err)
+		}
+
+		err = json.Unmarshal(data, &r.cassette)
+		if err != nil {
+			return nil, fmt.Errorf("recorder: decode cassette %s: %w", path, err)
+		}
+
+		// Cassettes are stored indented and may be edited by hand.
+		for _, interaction := range r.cassette.Interactions {
+			interaction.Request.Body, err = normalizeBody(interaction.Request.Body)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return r, nil
+}
+
+// Mode returns the mode the Recorder was created with.
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+// Interactions returns the interactions currently held by the cassette.
+func (r *Recorder) Interactions() []*Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]*Interaction(nil), r.cassette.Interactions...)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch r.mode {
+	case ModePassthrough:
+		return r.next.RoundTrip(req)
+	case ModeRecord:
+		return r.record(req)
+	case ModeReplay:
+		return r.replay(req)
+	default:
+		return nil, fmt.Errorf("recorder: unknown mode %s", r.mode)
+	}
+}
+
+// Close flushes a recording cassette to disk. It is a no-op in other modes.
+func (r *Recorder) Close() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.save()
+}
+
+func (r *Recorder) record(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := readBody(&resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	normalized, err := normalizeBody(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	interaction := &Interaction{
+		Request: RecordedRequest{
+			Method:   req.Method,
+			Endpoint: req.URL.Path,
+			Header:   redactHeader(req.Header),
+			Body:     normalized,
+		},
+		Response: RecordedResponse{
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header.Clone(),
+		},
+	}
+
+	if json.Valid(respBody) {
+		interaction.Response.Body = respBody
+	} else {
+		interaction.Response.RawBody = string(respBody)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+
+	err = r.save()
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+func (r *Recorder) replay(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(&req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	normalized, err := normalizeBody(reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Identical requests are served in recording order; once all of them
+	// have been used, the last one keeps being replayed.
+	last := -1
+
+	for i, interaction := range r.cassette.Interactions {
+		if !matches(interaction.Request, req.Method, req.URL.Path, normalized) {
+			continue
+		}
+
+		last = i
+
+		if !r.used[i] {
+			break
+		}
+	}
+
+	if last < 0 {
+		return nil, fmt.Errorf("%w: %s %s %s", ErrNoInteraction, req.Method, req.URL.Path, normalized)
+	}
+
+	r.used[last] = true
+
+	return r.cassette.Interactions[last].Response.toHTTP(req), nil
+}
+
+func (r *Recorder) save() error {
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(r.path), 0o755)
+	if err != nil {
+		return fmt.Errorf("recorder: create cassette dir: %w", err)
+	}
+
+	return os.WriteFile(r.path, data, 0o644)
+}
+
+func (rr RecordedResponse) toHTTP(req *http.Request) *http.Response {
+	body := []byte(rr.RawBody)
+	if len(rr.Body) != 0 {
+		body = rr.Body
+	}
+
+	header := http.Header(rr.Header).Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+
+	// The body may have been re-indented when the cassette was saved.
+	header.Del("Content-Length")
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rr.StatusCode, http.StatusText(rr.StatusCode)),
+		StatusCode:    rr.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
+
+func matches(recorded RecordedRequest, method, endpoint string, body json.RawMessage) bool {
+	return recorded.Method == method &&
+		recorded.Endpoint == endpoint &&
+		bytes.Equal(recorded.Body, body)
+}
+
+// readBody drains body and replaces it with an in-memory copy.
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+
+	data, err := io.ReadAll(*body)
+	if err != nil {
+		return nil, err
+	}
+
+	err = (*body).Close()
+	if err != nil {
+		return nil, err
+	}
+
+	*body = io.NopCloser(bytes.NewReader(data))
+
+	return data, nil
+}
+
+// normalizeBody re-encodes a JSON body with sorted keys and redacted secrets
+// so that logically equal requests compare equal.
+func normalizeBody(body []byte) (json.RawMessage, error) {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 {
+		return nil, nil
+	}
+
+	var data interface{}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	err := decoder.Decode(&data)
+	if err != nil {
+		return nil, fmt.Errorf("recorder: request body is not JSON: %w", err)
+	}
+
+	if obj, ok := data.(map[string]interface{}); ok {
+		for _, field := range redactedBodyFields {
+			if _, ok := obj[field]; ok {
+				obj[field] = redacted
+			}
+		}
+	}
+
+	return json.Marshal(data)
+}
+
+func redactHeader(header http.Header) map[string][]string {
+	result := header.Clone()
+	if result.Get("Authorization") != "" {
+		result.Set("Authorization", redacted)
+	}
+
+	return result
+}
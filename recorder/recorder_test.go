@@ -0,0 +1,144 @@
+package recorder_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/zfullio/gosmartis"
+	"github.com/zfullio/gosmartis/recorder"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// upstream answers every request with the next of the given bodies.
+func upstream(t *testing.T, bodies ...string) (http.RoundTripper, *int) {
+	t.Helper()
+
+	calls := 0
+
+	return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body := bodies[calls%len(bodies)]
+		calls++
+
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	}), &calls
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassettes", "crm.json")
+	ctx := context.Background()
+
+	next, calls := upstream(t,
+		`{"crmCustomFields":[{"id":1,"custom_field_title":"City"}]}`,
+		`{"crmCustomFields":[{"id":1,"custom_field_title":"Town"}]}`,
+	)
+
+	rec, err := recorder.New(path, recorder.ModeRecord, next)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := gosmartis.NewClient("secret-api-key", "secret-crm-token", &http.Client{Transport: rec})
+
+	for i := 0; i < 2; i++ {
+		_, err = client.GetCRMCustomFields(ctx, []int{1})
+		if err != nil {
+			t.Fatalf("record call %d: %v", i, err)
+		}
+	}
+
+	err = rec.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, secret := range []string{"secret-api-key", "secret-crm-token"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("cassette contains %s", secret)
+		}
+	}
+
+	rec, err = recorder.New(path, recorder.ModeReplay, roundTripFunc(func(*http.Request) (*http.Response, error) {
+		t.Error("replay reached the network")
+
+		return nil, errors.New("network")
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Replaying with a different token still matches the redacted body.
+	client = gosmartis.NewClient("other-key", "other-token", &http.Client{Transport: rec})
+
+	for _, want := range []string{"City", "Town", "Town"} {
+		fields, err := client.GetCRMCustomFields(ctx, []int{1})
+		if err != nil {
+			t.Fatalf("replay: %v", err)
+		}
+
+		if len(fields) != 1 || fields[0].CustomFieldTitle != want {
+			t.Errorf("replayed %+v, want title %s", fields, want)
+		}
+	}
+
+	if *calls != 2 {
+		t.Errorf("upstream calls = %d, want 2", *calls)
+	}
+
+	_, err = client.GetCRMCustomFields(ctx, []int{2})
+	if !errors.Is(err, recorder.ErrNoInteraction) {
+		t.Errorf("unrecorded request: err = %v, want ErrNoInteraction", err)
+	}
+}
+
+func TestReplayMissingCassette(t *testing.T) {
+	_, err := recorder.New(filepath.Join(t.TempDir(), "missing.json"), recorder.ModeReplay, nil)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("err = %v, want ErrNotExist", err)
+	}
+}
+
+func TestPassthrough(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "unused.json")
+	next, calls := upstream(t, `{"projects":[]}`)
+
+	rec, err := recorder.New(path, recorder.ModePassthrough, next)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := gosmartis.NewClient("key", "", &http.Client{Transport: rec})
+
+	_, err = client.GetProjects(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *calls != 1 || len(rec.Interactions()) != 0 {
+		t.Errorf("calls = %d, interactions = %d; want 1, 0", *calls, len(rec.Interactions()))
+	}
+
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("passthrough wrote a cassette: %v", err)
+	}
+}
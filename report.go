@@ -2,7 +2,6 @@ package gosmartis
 
 import (
 	"context"
-	"fmt"
 	"strconv"
 	"strings"
 )
@@ -31,11 +30,7 @@ func (r *Report) IsMapped() bool {
 	return r.isMapped
 }
 
-func (r *Report) GetColumnsNames(ctx context.Context, client *Client) error {
-	if client.CRMToken == "" {
-		return fmt.Errorf("crm token is empty")
-	}
-
+func (r *Report) GetColumnsNames(ctx context.Context, client API) error {
 	crmCustomFieldIDs := make(map[string][]*Cell)
 	crmCustomFieldGroupIDS := make(map[string][]*Cell)
 	for _, row := range r.RowsMassive {
@@ -81,7 +76,7 @@ func (r *Report) GetColumnsNames(ctx context.Context, client *Client) error {
 
 			crmCustomFieldGroupIDSList = append(crmCustomFieldGroupIDSList, idInt)
 		}
-		customFieldGroupNames, err := client.GetCRMCustomFieldGroups(context.Background(), crmCustomFieldGroupIDSList)
+		customFieldGroupNames, err := client.GetCRMCustomFieldGroups(ctx, crmCustomFieldGroupIDSList)
 		if err != nil {
 			return err
 		}
@@ -126,6 +121,18 @@ type Cell struct {
 	Type     CellType
 }
 
+// NewCell creates a cell and detects its type from the column ID.
+func NewCell(columnID string, value interface{}, name string) *Cell {
+	cell := Cell{
+		ColumnID: columnID,
+		Value:    value,
+		Name:     name,
+	}
+	cell.initType()
+
+	return &cell
+}
+
 func (c *Cell) initType() {
 	if strings.Contains(c.ColumnID, "field_") && !strings.Contains(c.ColumnID, "field_cf_group_") {
 		c.Type = CellTypeField
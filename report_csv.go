@@ -0,0 +1,257 @@
+package gosmartis
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type HeaderMode int
+
+const (
+	HeaderColumnID HeaderMode = iota // Raw Cell.ColumnID
+	HeaderName                       // Resolved Cell.Name, ColumnID when the name is unknown
+)
+
+// Locale controls how numbers and dates are rendered in exported reports.
+type Locale struct {
+	DecimalSeparator   string
+	ThousandsSeparator string
+	// DateLayout re-formats values that look like dates. Empty keeps them as is.
+	DateLayout string
+	// DateTimeLayout re-formats values that look like date-times. Empty keeps them as is.
+	DateTimeLayout string
+}
+
+var (
+	LocaleDefault = Locale{DecimalSeparator: "."}
+	LocaleRU      = Locale{
+		DecimalSeparator:   ",",
+		ThousandsSeparator: " ",
+		DateLayout:         "02.01.2006",
+		DateTimeLayout:     "02.01.2006 15:04:05",
+	}
+	LocaleEN = Locale{
+		DecimalSeparator:   ".",
+		ThousandsSeparator: ",",
+		DateLayout:         "01/02/2006",
+		DateTimeLayout:     "01/02/2006 15:04:05",
+	}
+)
+
+type CSVOptions struct {
+	Header HeaderMode
+	// Columns fixes the column set and order by ColumnID.
+	// By default every column of the report is written in a stable order.
+	Columns []string
+	// Delimiter defaults to a comma.
+	Delimiter rune
+	// Null is written for missing cells and nil values.
+	Null string
+	// Locale defaults to LocaleDefault.
+	Locale *Locale
+	// NoHeader skips the header line.
+	NoHeader bool
+}
+
+// WriteCSV writes the report rows to w as CSV.
+// Rows are encoded one by one straight from RowsMassive, so the report is
+// never copied in memory.
+func (r *Report) WriteCSV(w io.Writer, opts CSVOptions) error {
+	columns := opts.Columns
+	if len(columns) == 0 {
+		columns = r.Columns()
+	}
+
+	locale := LocaleDefault
+	if opts.Locale != nil {
+		locale = *opts.Locale
+	}
+
+	writer := csv.NewWriter(w)
+	if opts.Delimiter != 0 {
+		writer.Comma = opts.Delimiter
+	}
+
+	record := make([]string, len(columns))
+
+	if !opts.NoHeader {
+		names := r.columnNames()
+
+		for i, col := range columns {
+			record[i] = col
+			if opts.Header == HeaderName && names[col] != "" {
+				record[i] = names[col]
+			}
+		}
+
+		err := writer.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+
+	index := make(map[string]int, len(columns))
+	for i, col := range columns {
+		index[col] = i
+	}
+
+	for _, row := range r.RowsMassive {
+		for i := range record {
+			record[i] = opts.Null
+		}
+
+		for _, cell := range row {
+			i, ok := index[cell.ColumnID]
+			if !ok {
+				continue
+			}
+
+			record[i] = locale.format(cell.Value, opts.Null)
+		}
+
+		err := writer.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+// WriteTSV is WriteCSV with a tab delimiter.
+func (r *Report) WriteTSV(w io.Writer, opts CSVOptions) error {
+	opts.Delimiter = '\t'
+
+	return r.WriteCSV(w, opts)
+}
+
+// Columns returns the ColumnIDs present in the report.
+// System fields come first, then CRM fields and CRM field groups;
+// within each kind columns are sorted by ID.
+func (r *Report) Columns() []string {
+	types := make(map[string]CellType)
+
+	for _, row := range r.RowsMassive {
+		for _, cell := range row {
+			types[cell.ColumnID] = cell.Type
+		}
+	}
+
+	columns := make([]string, 0, len(types))
+	for col := range types {
+		columns = append(columns, col)
+	}
+
+	sortColumns(columns, types)
+
+	return columns
+}
+
+func (r *Report) columnNames() map[string]string {
+	names := make(map[string]string)
+
+	for _, row := range r.RowsMassive {
+		for _, cell := range row {
+			if cell.Name != "" {
+				names[cell.ColumnID] = cell.Name
+			}
+		}
+	}
+
+	return names
+}
+
+func (l Locale) format(value interface{}, null string) string {
+	switch v := value.(type) {
+	case nil:
+		return null
+	case string:
+		return l.formatString(v)
+	case float64:
+		return l.formatNumber(strconv.FormatFloat(v, 'f', -1, 64))
+	case json.Number:
+		return l.formatNumber(v.String())
+	case int:
+		return l.formatNumber(strconv.Itoa(v))
+	case int64:
+		return l.formatNumber(strconv.FormatInt(v, 10))
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		if l.DateTimeLayout != "" {
+			return v.Format(l.DateTimeLayout)
+		}
+
+		return v.Format(smartisDateTimeLayout)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+
+		return string(data)
+	}
+}
+
+func (l Locale) formatString(s string) string {
+	if l.DateLayout != "" {
+		if t, ok := ParseDate(s); ok {
+			return t.Format(l.DateLayout)
+		}
+	}
+
+	if l.DateTimeLayout != "" {
+		if t, ok := ParseDateTime(s); ok {
+			return t.Format(l.DateTimeLayout)
+		}
+	}
+
+	return s
+}
+
+// formatNumber localizes a plain decimal representation such as "-1234.5".
+func (l Locale) formatNumber(s string) string {
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+
+	intPart, fracPart, hasFrac := strings.Cut(s, ".")
+
+	if l.ThousandsSeparator != "" && len(intPart) > 3 {
+		var b strings.Builder
+
+		head := len(intPart) % 3
+		if head > 0 {
+			b.WriteString(intPart[:head])
+		}
+
+		for i := head; i < len(intPart); i += 3 {
+			if b.Len() > 0 {
+				b.WriteString(l.ThousandsSeparator)
+			}
+
+			b.WriteString(intPart[i : i+3])
+		}
+
+		intPart = b.String()
+	}
+
+	if !hasFrac {
+		return sign + intPart
+	}
+
+	decimal := l.DecimalSeparator
+	if decimal == "" {
+		decimal = "."
+	}
+
+	return sign + intPart + decimal + fracPart
+}
@@ -0,0 +1,103 @@
+package gosmartis
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestLocaleFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		locale Locale
+		value  interface{}
+		want   string
+	}{
+		{"nil", LocaleDefault, nil, "NULL"},
+		{"default float", LocaleDefault, 1234567.25, "1234567.25"},
+		{"default negative", LocaleDefault, -0.5, "-0.5"},
+		{"ru float", LocaleRU, 1234567.25, "1\u00a0234\u00a0567,25"},
+		{"ru negative", LocaleRU, -1234.5, "-1\u00a0234,5"},
+		{"ru short", LocaleRU, 999.0, "999"},
+		{"ru exact thousands", LocaleRU, 100000.0, "100\u00a0000"},
+		{"en int", LocaleEN, 1234567, "1,234,567"},
+		{"en int64", LocaleEN, int64(-1000), "-1,000"},
+		{"en json number", LocaleEN, json.Number("12345.678"), "12,345.678"},
+		{"ru date", LocaleRU, "2024-01-31", "31.01.2024"},
+		{"en datetime", LocaleEN, "2024-01-31 23:59:58", "01/31/2024 23:59:58"},
+		{"default date kept", LocaleDefault, "2024-01-31", "2024-01-31"},
+		{"plain string", LocaleRU, "utm 2024-01-31", "utm 2024-01-31"},
+		{"bool", LocaleRU, true, "true"},
+		{"time", LocaleRU, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), "02.01.2024 03:04:05"},
+		{"time default", LocaleDefault, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), "2024-01-02 03:04:05"},
+		{"object", LocaleDefault, map[string]interface{}{"a": 1.0}, `{"a":1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.locale.format(tt.value, "NULL"); got != tt.want {
+				t.Errorf("format(%v) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	report := &Report{Metric: "leads", RowsMassive: []Row{
+		{NewCell("day", "2024-01-01", ""), NewCell("leads", 1500.5, ""), NewCell("field_2", "Moscow", "City")},
+		{NewCell("day", "2024-01-02", ""), NewCell("leads", nil, "")},
+	}}
+
+	tests := []struct {
+		name string
+		opts CSVOptions
+		want string
+	}{
+		{
+			name: "ids",
+			opts: CSVOptions{},
+			want: "day,leads,field_2\n2024-01-01,1500.5,Moscow\n2024-01-02,,\n",
+		},
+		{
+			name: "names ru",
+			opts: CSVOptions{Header: HeaderName, Locale: &LocaleRU, Delimiter: ';', Null: "-"},
+			want: "day;leads;City\n01.01.2024;1\u00a0500,5;Moscow\n02.01.2024;-;-\n",
+		},
+		{
+			name: "columns without header",
+			opts: CSVOptions{Columns: []string{"leads", "day"}, NoHeader: true},
+			want: "1500.5,2024-01-01\n,2024-01-02\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			err := report.WriteCSV(&buf, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if buf.String() != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteTSV(t *testing.T) {
+	report := &Report{RowsMassive: []Row{{NewCell("day", "2024-01-01", ""), NewCell("cost", 2.0, "")}}}
+
+	var buf bytes.Buffer
+
+	err := report.WriteTSV(&buf, CSVOptions{Delimiter: ','})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "cost\tday\n2\t2024-01-01\n"; buf.String() != want {
+		t.Errorf("got %q, want %q", buf.String(), want)
+	}
+}
@@ -45,12 +45,12 @@ type GetGroupingsResponse struct {
 func (g *GetGroupingsResponse) UnmarshalJSON(b []byte) error {
 	var data struct {
 		Groupings map[string]struct {
-			ID       int    `json:"id"`
-			Title    string `json:"title"`
-			Code     string `json:"code"`
-			IsSystem bool   `json:"is_system"`
-			Sort     int    `json:"sort"`
-			ClientID int    `json:"client_id"`
+			ID       int      `json:"id"`
+			Title    string   `json:"title"`
+			Code     string   `json:"code"`
+			IsSystem FlexBool `json:"is_system"`
+			Sort     int      `json:"sort"`
+			ClientID int      `json:"client_id"`
 		} `json:"groupings"`
 	}
 
@@ -82,10 +82,10 @@ type GetAttributionsResponse struct {
 func (g *GetAttributionsResponse) UnmarshalJSON(b []byte) error {
 	var data struct {
 		ModelAttributions map[string]struct {
-			ID       int    `json:"id"`
-			IsSystem int    `json:"is_system"`
-			About    string `json:"about"`
-			Title    string `json:"title"`
+			ID       int      `json:"id"`
+			IsSystem FlexBool `json:"is_system"`
+			About    string   `json:"about"`
+			Title    string   `json:"title"`
 		} `json:"modelAttributions"`
 	}
 
@@ -96,11 +96,10 @@ func (g *GetAttributionsResponse) UnmarshalJSON(b []byte) error {
 	attributions := make([]AttributionSmartis, 0, len(data.ModelAttributions))
 
 	for _, v := range data.ModelAttributions {
-		isSystem := v.IsSystem == 1
 		attributions = append(attributions, AttributionSmartis{
 			About:    v.About,
 			ID:       v.ID,
-			IsSystem: isSystem,
+			IsSystem: v.IsSystem,
 			Title:    v.Title,
 		})
 	}
@@ -0,0 +1,457 @@
+// Package clickhouse loads raw Smartis reports into ClickHouse.
+//
+// Rows are written with the native batch protocol into a MergeTree table
+// partitioned by report day. The report day of a row is its "day" column
+// when the report is grouped by day. ReplaceDay swaps a whole day partition
+// atomically, so backfills never leave duplicated or half-written days.
+package clickhouse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+
+	"github.com/zfullio/gosmartis"
+)
+
+// Columns added to every row next to the report columns.
+const (
+	MetricColumn = "metric"
+	DayColumn    = "report_day"
+)
+
+// ErrDayMismatch is returned by ReplaceDay for rows of another day.
+var ErrDayMismatch = errors.New("row day does not match the replaced day")
+
+// DefaultBatchSize is the number of rows per insert used when
+// Options.BatchSize is zero.
+const DefaultBatchSize = 100_000
+
+type Options struct {
+	// Database of the table. Empty uses the connection default.
+	Database string
+	// BatchSize limits the number of rows sent per insert.
+	BatchSize int
+}
+
+type Sink struct {
+	conn  driver.Conn
+	table string
+	opts  Options
+}
+
+// New returns a Sink writing to table over conn, which is typically
+// opened with clickhouse.Open.
+func New(conn driver.Conn, table string, opts Options) *Sink {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+
+	return &Sink{
+		conn:  conn,
+		table: table,
+		opts:  opts,
+	}
+}
+
+// Load appends the rows of the reports to the partitions of their days.
+// Rows without a day column go to the partition of day.
+func (s *Sink) Load(ctx context.Context, day time.Time, reports ...*gosmartis.Report) error {
+	columns, err := s.prepare(ctx, reports)
+	if err != nil {
+		return err
+	}
+
+	return s.insert(ctx, s.qualified(s.table), day, columns, reports)
+}
+
+// ReplaceDay replaces the whole partition of day with the rows of the
+// reports. The rows are staged in a scratch table and swapped in with
+// REPLACE PARTITION, so readers see either the old or the new day. Rows of
+// other days fail with ErrDayMismatch before anything is written.
+func (s *Sink) ReplaceDay(ctx context.Context, day time.Time, reports ...*gosmartis.Report) error {
+	day = truncateDay(day)
+
+	err := checkDay(reports, day)
+	if err != nil {
+		return err
+	}
+
+	columns, err := s.prepare(ctx, reports)
+	if err != nil {
+		return err
+	}
+
+	partition := partitionID(day)
+
+	if countRows(reports) == 0 {
+		return s.conn.Exec(ctx, fmt.Sprintf(
+			"ALTER TABLE %s DROP PARTITION ID '%s'", s.qualified(s.table), partition,
+		))
+	}
+
+	staging := s.qualified(s.table + "_staging_" + partition)
+
+	err = s.conn.Exec(ctx, fmt.Sprintf("DROP TABLE IF EXISTS %s", staging))
+	if err != nil {
+		return err
+	}
+
+	err = s.conn.Exec(ctx, fmt.Sprintf("CREATE TABLE %s AS %s", staging, s.qualified(s.table)))
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = s.conn.Exec(context.WithoutCancel(ctx), fmt.Sprintf("DROP TABLE IF EXISTS %s", staging))
+	}()
+
+	err = s.insert(ctx, staging, day, columns, reports)
+	if err != nil {
+		return err
+	}
+
+	return s.conn.Exec(ctx, fmt.Sprintf(
+		"ALTER TABLE %s REPLACE PARTITION ID '%s' FROM %s", s.qualified(s.table), partition, staging,
+	))
+}
+
+// ReplaceDays replaces the partitions of several days. Days are swapped one
+// by one, each of them atomically.
+func (s *Sink) ReplaceDays(ctx context.Context, days map[time.Time][]*gosmartis.Report) error {
+	for day, reports := range days {
+		err := s.ReplaceDay(ctx, day, reports...)
+		if err != nil {
+			return fmt.Errorf("clickhouse: replace %s: %w", day.Format(time.DateOnly), err)
+		}
+	}
+
+	return nil
+}
+
+// prepare creates or migrates the table and returns the columns to insert,
+// typed after the table columns.
+func (s *Sink) prepare(ctx context.Context, reports []*gosmartis.Report) ([]gosmartis.ColumnInfo, error) {
+	var rows []gosmartis.Row
+	for _, report := range reports {
+		rows = append(rows, report.RowsMassive...)
+	}
+
+	columns := gosmartis.InferColumns(rows)
+
+	err := s.migrate(ctx, columns)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.columnKinds(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, col := range columns {
+		if kind, ok := existing[col.ID]; ok {
+			columns[i].Kind = kind
+		}
+	}
+
+	return columns, nil
+}
+
+func (s *Sink) migrate(ctx context.Context, columns []gosmartis.ColumnInfo) error {
+	defs := []string{
+		quote(MetricColumn) + " LowCardinality(String)",
+		quote(DayColumn) + " Date",
+	}
+
+	for _, col := range columns {
+		defs = append(defs, columnDef(col))
+	}
+
+	err := s.conn.Exec(ctx, fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (%s) ENGINE = MergeTree PARTITION BY %s ORDER BY (%s, %s)",
+		s.qualified(s.table), strings.Join(defs, ", "), quote(DayColumn), quote(MetricColumn), quote(DayColumn),
+	))
+	if err != nil {
+		return err
+	}
+
+	for _, col := range columns {
+		err = s.conn.Exec(ctx, fmt.Sprintf(
+			"ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s", s.qualified(s.table), columnDef(col),
+		))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Sink) columnKinds(ctx context.Context) (map[string]gosmartis.ColumnKind, error) {
+	database := "currentDatabase()"
+	if s.opts.Database != "" {
+		database = "'" + escape(s.opts.Database) + "'"
+	}
+
+	rows, err := s.conn.Query(ctx, fmt.Sprintf(
+		"SELECT name, type FROM system.columns WHERE database = %s AND table = '%s'",
+		database, escape(s.table),
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	kinds := make(map[string]gosmartis.ColumnKind)
+
+	for rows.Next() {
+		var name, typ string
+
+		err = rows.Scan(&name, &typ)
+		if err != nil {
+			return nil, err
+		}
+
+		kinds[name] = columnKind(typ)
+	}
+
+	return kinds, rows.Err()
+}
+
+func (s *Sink) insert(ctx context.Context, table string, day time.Time, columns []gosmartis.ColumnInfo, reports []*gosmartis.Report) error {
+	names := []string{quote(MetricColumn), quote(DayColumn)}
+	index := make(map[string]int, len(columns))
+
+	for i, col := range columns {
+		names = append(names, quote(col.ID))
+		index[col.ID] = i + 2
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s)", table, strings.Join(names, ", "))
+	day = truncateDay(day)
+
+	var (
+		batch   driver.Batch
+		pending int
+		err     error
+	)
+
+	for _, report := range reports {
+		for n, row := range report.RowsMassive {
+			if batch == nil {
+				batch, err = s.conn.PrepareBatch(ctx, query)
+				if err != nil {
+					return err
+				}
+			}
+
+			values := make([]interface{}, len(names))
+			values[0] = report.Metric
+			values[1] = day
+
+			if rowDay, ok := dayOf(row); ok {
+				values[1] = rowDay
+			}
+
+			for _, cell := range row {
+				i, ok := index[cell.ColumnID]
+				if !ok || cell.Value == nil {
+					continue
+				}
+
+				values[i], err = convert(columns[i-2], cell.Value)
+				if err != nil {
+					_ = batch.Abort()
+
+					return fmt.Errorf("clickhouse: %s row %d: %w", report.Metric, n, err)
+				}
+			}
+
+			err = batch.Append(values...)
+			if err != nil {
+				_ = batch.Abort()
+
+				return err
+			}
+
+			pending++
+
+			if pending >= s.opts.BatchSize {
+				err = batch.Send()
+				if err != nil {
+					return err
+				}
+
+				batch, pending = nil, 0
+			}
+		}
+	}
+
+	if batch == nil {
+		return nil
+	}
+
+	return batch.Send()
+}
+
+func (s *Sink) qualified(table string) string {
+	if s.opts.Database == "" {
+		return quote(table)
+	}
+
+	return quote(s.opts.Database) + "." + quote(table)
+}
+
+func columnDef(col gosmartis.ColumnInfo) string {
+	return quote(col.ID) + " " + chType(col)
+}
+
+func chType(col gosmartis.ColumnInfo) string {
+	switch col.Kind {
+	case gosmartis.ColumnInt:
+		return "Nullable(Int64)"
+	case gosmartis.ColumnFloat:
+		return "Nullable(Float64)"
+	case gosmartis.ColumnBool:
+		return "Nullable(Bool)"
+	case gosmartis.ColumnDate:
+		return "Nullable(Date)"
+	case gosmartis.ColumnDateTime:
+		return "Nullable(DateTime)"
+	default:
+		if col.Dimension {
+			return "LowCardinality(Nullable(String))"
+		}
+
+		return "Nullable(String)"
+	}
+}
+
+func columnKind(chType string) gosmartis.ColumnKind {
+	chType = strings.TrimSuffix(strings.TrimPrefix(chType, "LowCardinality("), ")")
+	chType = strings.TrimSuffix(strings.TrimPrefix(chType, "Nullable("), ")")
+
+	switch {
+	case strings.HasPrefix(chType, "Int"), strings.HasPrefix(chType, "UInt"):
+		return gosmartis.ColumnInt
+	case strings.HasPrefix(chType, "Float"), strings.HasPrefix(chType, "Decimal"):
+		return gosmartis.ColumnFloat
+	case chType == "Bool":
+		return gosmartis.ColumnBool
+	case chType == "Date", chType == "Date32":
+		return gosmartis.ColumnDate
+	case strings.HasPrefix(chType, "DateTime"):
+		return gosmartis.ColumnDateTime
+	default:
+		return gosmartis.ColumnString
+	}
+}
+
+func convert(col gosmartis.ColumnInfo, value interface{}) (interface{}, error) {
+	var (
+		result interface{}
+		ok     bool
+	)
+
+	switch col.Kind {
+	case gosmartis.ColumnInt:
+		result, ok = gosmartis.CellInt(value)
+	case gosmartis.ColumnFloat:
+		result, ok = gosmartis.CellFloat(value)
+	case gosmartis.ColumnBool:
+		result, ok = value.(bool)
+	case gosmartis.ColumnDate:
+		result, ok = timeValue(value, gosmartis.ParseDate)
+	case gosmartis.ColumnDateTime:
+		result, ok = timeValue(value, gosmartis.ParseDateTime)
+	default:
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		default:
+			return fmt.Sprint(v), nil
+		}
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("column %s: cannot store %T value %v as %s", col.ID, value, value, col.Kind)
+	}
+
+	return result, nil
+}
+
+func timeValue(value interface{}, parse func(string) (time.Time, bool)) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		return parse(v)
+	default:
+		return time.Time{}, false
+	}
+}
+
+// dayOf returns the value of the day column of row.
+func dayOf(row gosmartis.Row) (time.Time, bool) {
+	value := row.Value(string(gosmartis.GroupByDay))
+	if value == nil {
+		return time.Time{}, false
+	}
+
+	t, ok := timeValue(value, gosmartis.ParseDate)
+	if !ok {
+		t, ok = timeValue(value, gosmartis.ParseDateTime)
+	}
+
+	return truncateDay(t), ok
+}
+
+// checkDay rejects rows whose day column is not day.
+func checkDay(reports []*gosmartis.Report, day time.Time) error {
+	for _, report := range reports {
+		for n, row := range report.RowsMassive {
+			rowDay, ok := dayOf(row)
+			if ok && !rowDay.Equal(day) {
+				return fmt.Errorf("clickhouse: %s row %d: %w: %s, not %s",
+					report.Metric, n, ErrDayMismatch, rowDay.Format(time.DateOnly), day.Format(time.DateOnly))
+			}
+		}
+	}
+
+	return nil
+}
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// partitionID is the ClickHouse partition ID of a Date partition key.
+func partitionID(day time.Time) string {
+	return day.Format("20060102")
+}
+
+func countRows(reports []*gosmartis.Report) int {
+	n := 0
+	for _, report := range reports {
+		n += len(report.RowsMassive)
+	}
+
+	return n
+}
+
+func quote(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "\\`") + "`"
+}
+
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s)
+}
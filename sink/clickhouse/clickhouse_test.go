@@ -0,0 +1,151 @@
+package clickhouse_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+
+	"github.com/zfullio/gosmartis"
+	chsink "github.com/zfullio/gosmartis/sink/clickhouse"
+)
+
+// The tests run against the server in SMARTIS_TEST_CLICKHOUSE_DSN, e.g.
+//
+//	docker run --rm -p 9000:9000 clickhouse/clickhouse-server
+//	SMARTIS_TEST_CLICKHOUSE_DSN=clickhouse://localhost:9000/default go test ./sink/clickhouse
+const envDSN = "SMARTIS_TEST_CLICKHOUSE_DSN"
+
+func connect(t *testing.T) (driver.Conn, string) {
+	t.Helper()
+
+	dsn := os.Getenv(envDSN)
+	if dsn == "" {
+		t.Skipf("%s is not set", envDSN)
+	}
+
+	opts, err := clickhouse.ParseDSN(dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := clickhouse.Open(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	table := fmt.Sprintf("gosmartis_test_%d", time.Now().UnixNano())
+
+	t.Cleanup(func() {
+		_ = conn.Exec(context.Background(), "DROP TABLE IF EXISTS `"+table+"`")
+		_ = conn.Close()
+	})
+
+	return conn, table
+}
+
+func report(metric string, rows ...gosmartis.Row) *gosmartis.Report {
+	return &gosmartis.Report{Metric: metric, RowsMassive: rows}
+}
+
+func row(day string, leads float64) gosmartis.Row {
+	return gosmartis.Row{gosmartis.NewCell("day", day, ""), gosmartis.NewCell("leads", leads, "")}
+}
+
+// days returns the number of rows per report day.
+func days(t *testing.T, conn driver.Conn, table string) map[string]uint64 {
+	t.Helper()
+
+	rows, err := conn.Query(context.Background(), fmt.Sprintf(
+		"SELECT toString(%s), count() FROM `%s` GROUP BY %s", chsink.DayColumn, table, chsink.DayColumn,
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer rows.Close()
+
+	result := make(map[string]uint64)
+
+	for rows.Next() {
+		var (
+			day string
+			n   uint64
+		)
+
+		err = rows.Scan(&day, &n)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result[day] = n
+	}
+
+	return result
+}
+
+func TestLoadPartitionsByRowDay(t *testing.T) {
+	conn, table := connect(t)
+	ctx := context.Background()
+	sink := chsink.New(conn, table, chsink.Options{})
+
+	err := sink.Load(ctx, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		report("leads", row("2024-01-01", 1), row("2024-01-02", 2), row("2024-01-02", 3)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := days(t, conn, table)
+	if got["2024-01-01"] != 1 || got["2024-01-02"] != 2 || len(got) != 2 {
+		t.Errorf("rows per day = %v, want 2024-01-01:1 2024-01-02:2", got)
+	}
+}
+
+func TestReplaceDay(t *testing.T) {
+	conn, table := connect(t)
+	ctx := context.Background()
+	sink := chsink.New(conn, table, chsink.Options{})
+
+	first := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	second := first.AddDate(0, 0, 1)
+
+	err := sink.Load(ctx, first, report("leads", row("2024-01-01", 1), row("2024-01-02", 5)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replacement := report("leads", row("2024-01-01", 2), row("2024-01-01", 3))
+
+	for i := 0; i < 2; i++ {
+		err = sink.ReplaceDay(ctx, first, replacement)
+		if err != nil {
+			t.Fatalf("replace %d: %v", i, err)
+		}
+	}
+
+	got := days(t, conn, table)
+	if got["2024-01-01"] != 2 || got["2024-01-02"] != 1 {
+		t.Errorf("after replace = %v, want 2024-01-01:2 2024-01-02:1", got)
+	}
+
+	err = sink.ReplaceDay(ctx, first, report("leads", row("2024-01-02", 9)))
+	if !errors.Is(err, chsink.ErrDayMismatch) {
+		t.Errorf("replace with another day: err = %v, want ErrDayMismatch", err)
+	}
+
+	// An empty day drops the partition.
+	err = sink.ReplaceDay(ctx, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got = days(t, conn, table)
+	if _, ok := got["2024-01-02"]; ok || got["2024-01-01"] != 2 {
+		t.Errorf("after empty replace = %v, want only 2024-01-01:2", got)
+	}
+}
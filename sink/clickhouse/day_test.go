@@ -0,0 +1,50 @@
+package clickhouse
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zfullio/gosmartis"
+)
+
+func TestDayOf(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+		ok    bool
+	}{
+		{"2024-03-05", "2024-03-05", true},
+		{"2024-03-05 23:10:00", "2024-03-05", true},
+		{time.Date(2024, 3, 5, 12, 0, 0, 0, time.UTC), "2024-03-05", true},
+		{nil, "", false},
+		{"yesterday", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := dayOf(gosmartis.Row{gosmartis.NewCell("day", tt.value, "")})
+		if ok != tt.ok || (ok && got.Format(time.DateOnly) != tt.want) {
+			t.Errorf("dayOf(%v) = %v, %v; want %s, %v", tt.value, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestCheckDay(t *testing.T) {
+	day := time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)
+	report := &gosmartis.Report{Metric: "leads", RowsMassive: []gosmartis.Row{
+		{gosmartis.NewCell("day", "2024-03-05", ""), gosmartis.NewCell("leads", 1.0, "")},
+		{gosmartis.NewCell("leads", 2.0, "")},
+	}}
+
+	err := checkDay([]*gosmartis.Report{report}, day)
+	if err != nil {
+		t.Errorf("matching day: %v", err)
+	}
+
+	report.RowsMassive = append(report.RowsMassive, gosmartis.Row{gosmartis.NewCell("day", "2024-03-06", "")})
+
+	err = checkDay([]*gosmartis.Report{report}, day)
+	if !errors.Is(err, ErrDayMismatch) {
+		t.Errorf("other day: err = %v, want ErrDayMismatch", err)
+	}
+}
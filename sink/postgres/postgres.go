@@ -0,0 +1,526 @@
+// Package postgres loads Smartis reports into PostgreSQL tables.
+//
+// A Sink creates the target table on first use and adds columns as reports
+// bring new ones. Every load runs in one transaction: rows are copied into a
+// temporary table with COPY and then upserted on the grouping keys, so
+// reloading the same period is idempotent. The keys are fixed by the first
+// load; reports sharing a table must have the same key columns.
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/zfullio/gosmartis"
+)
+
+// MetricColumn holds Report.Metric in every loaded row and is always part of
+// the upsert key, so several metrics can share a table.
+const MetricColumn = "metric"
+
+// stagingRowColumn numbers the rows of the staging table in report order.
+const stagingRowColumn = "gosmartis_staging_row"
+
+// DB is satisfied by *pgx.Conn and *pgxpool.Pool.
+type DB interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+type Options struct {
+	// Schema is the database schema of the table. Empty uses the search path.
+	Schema string
+	// KeyColumns are the report columns identifying a row, such as "day" or
+	// "ad_id". By default every dimension column of the report is a key.
+	KeyColumns []string
+}
+
+type Sink struct {
+	db    DB
+	table string
+	opts  Options
+}
+
+func New(db DB, table string, opts Options) *Sink {
+	return &Sink{
+		db:    db,
+		table: table,
+		opts:  opts,
+	}
+}
+
+// Load upserts the rows of the given reports in a single transaction.
+func (s *Sink) Load(ctx context.Context, reports ...*gosmartis.Report) (err error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback(ctx)
+		}
+	}()
+
+	for _, report := range reports {
+		err = s.load(ctx, tx, report)
+		if err != nil {
+			return fmt.Errorf("postgres: load %s: %w", report.Metric, err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (s *Sink) load(ctx context.Context, tx pgx.Tx, report *gosmartis.Report) error {
+	if len(report.RowsMassive) == 0 {
+		return nil
+	}
+
+	columns := report.ColumnInfos()
+
+	keys, err := s.keyColumns(columns)
+	if err != nil {
+		return err
+	}
+
+	err = s.migrate(ctx, tx, columns, keys)
+	if err != nil {
+		return err
+	}
+
+	// Columns created by earlier loads keep their type, values are
+	// converted to it.
+	existing, err := s.columnKinds(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	for i, col := range columns {
+		if kind, ok := existing[col.ID]; ok {
+			columns[i].Kind = kind
+		}
+	}
+
+	names := make([]string, 0, len(columns)+1)
+	names = append(names, MetricColumn)
+
+	for _, col := range columns {
+		names = append(names, col.ID)
+	}
+
+	staging := "gosmartis_staging_" + strings.ReplaceAll(s.table, ".", "_")
+
+	quotedNames := make([]string, 0, len(names))
+	for _, name := range names {
+		quotedNames = append(quotedNames, quote(name))
+	}
+
+	// The staging table has the types of the loaded columns only, without
+	// the NOT NULL constraints of the target, plus the row number.
+	_, err = tx.Exec(ctx, fmt.Sprintf(
+		"CREATE TEMP TABLE %s ON COMMIT DROP AS SELECT %s, NULL::bigint AS %s FROM %s WITH NO DATA",
+		quote(staging), strings.Join(quotedNames, ", "), quote(stagingRowColumn), s.qualified(),
+	))
+	if err != nil {
+		return err
+	}
+
+	rows, err := copyRows(report, columns, keys)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.CopyFrom(ctx, pgx.Identifier{staging}, append(names, stagingRowColumn), pgx.CopyFromRows(rows))
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, upsertSQL(s.qualified(), quote(staging), names, keys))
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, "DROP TABLE "+quote(staging))
+
+	return err
+}
+
+// keyColumns returns the upsert key, MetricColumn first.
+func (s *Sink) keyColumns(columns []gosmartis.ColumnInfo) ([]string, error) {
+	keys := []string{MetricColumn}
+
+	if len(s.opts.KeyColumns) == 0 {
+		for _, col := range columns {
+			if col.Dimension {
+				keys = append(keys, col.ID)
+			}
+		}
+
+		return keys, nil
+	}
+
+	known := make(map[string]bool, len(columns))
+	for _, col := range columns {
+		known[col.ID] = true
+	}
+
+	for _, key := range s.opts.KeyColumns {
+		if !known[key] {
+			return nil, fmt.Errorf("key column %q is not in the report", key)
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// migrate creates the table, adds missing columns and makes sure the unique
+// upsert index exists. A table has a single key set: loading a report with
+// other key columns fails with ErrKeyMismatch.
+func (s *Sink) migrate(ctx context.Context, tx pgx.Tx, columns []gosmartis.ColumnInfo, keys []string) error {
+	isKey := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		isKey[key] = true
+	}
+
+	defs := []string{quote(MetricColumn) + " text NOT NULL"}
+
+	for _, col := range columns {
+		defs = append(defs, columnDef(col, isKey[col.ID]))
+	}
+
+	_, err := tx.Exec(ctx, fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (%s)",
+		s.qualified(), strings.Join(defs, ", "),
+	))
+	if err != nil {
+		return err
+	}
+
+	for _, col := range columns {
+		// Columns added later cannot be NOT NULL on a non-empty table.
+		_, err = tx.Exec(ctx, fmt.Sprintf(
+			"ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s",
+			s.qualified(), columnDef(col, false),
+		))
+		if err != nil {
+			return err
+		}
+	}
+
+	existing, err := s.indexKeys(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	if existing != nil {
+		if !sameKeys(existing, keys) {
+			return fmt.Errorf("%w: table has %s, report has %s",
+				ErrKeyMismatch, strings.Join(existing, ", "), strings.Join(keys, ", "))
+		}
+
+		return nil
+	}
+
+	quoted := make([]string, 0, len(keys))
+	for _, key := range keys {
+		quoted = append(quoted, quote(key))
+	}
+
+	_, err = tx.Exec(ctx, fmt.Sprintf(
+		"CREATE UNIQUE INDEX %s ON %s (%s)",
+		quote(s.indexName()), s.qualified(), strings.Join(quoted, ", "),
+	))
+
+	return err
+}
+
+// indexKeys returns the columns of the upsert index, nil when the index
+// does not exist yet.
+func (s *Sink) indexKeys(ctx context.Context, tx pgx.Tx) ([]string, error) {
+	rows, err := tx.Query(ctx,
+		`SELECT a.attname
+		FROM pg_index i
+		JOIN pg_class c ON c.oid = i.indexrelid
+		JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+		WHERE i.indrelid = $1::regclass AND c.relname = $2
+		ORDER BY array_position(i.indkey::int2[], a.attnum)`,
+		s.qualified(), s.indexName(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var keys []string
+
+	for rows.Next() {
+		var key string
+
+		err = rows.Scan(&key)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+func sameKeys(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	set := make(map[string]bool, len(a))
+	for _, key := range a {
+		set[key] = true
+	}
+
+	for _, key := range b {
+		if !set[key] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *Sink) columnKinds(ctx context.Context, tx pgx.Tx) (map[string]gosmartis.ColumnKind, error) {
+	rows, err := tx.Query(ctx,
+		`SELECT a.attname, format_type(a.atttypid, NULL)
+		FROM pg_attribute a
+		WHERE a.attrelid = $1::regclass AND a.attnum > 0 AND NOT a.attisdropped`,
+		s.qualified(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	kinds := make(map[string]gosmartis.ColumnKind)
+
+	for rows.Next() {
+		var name, typ string
+
+		err = rows.Scan(&name, &typ)
+		if err != nil {
+			return nil, err
+		}
+
+		kinds[name] = columnKind(typ)
+	}
+
+	return kinds, rows.Err()
+}
+
+// indexName is the name of the upsert index of the table.
+func (s *Sink) indexName() string {
+	name := strings.ReplaceAll(s.table, ".", "_")
+	if len(name) > 40 {
+		name = name[:40]
+	}
+
+	return name + "_upsert"
+}
+
+func (s *Sink) qualified() string {
+	if s.opts.Schema == "" {
+		return quote(s.table)
+	}
+
+	return quote(s.opts.Schema) + "." + quote(s.table)
+}
+
+func upsertSQL(table, staging string, names, keys []string) string {
+	isKey := make(map[string]bool, len(keys))
+	quotedKeys := make([]string, 0, len(keys))
+
+	for _, key := range keys {
+		isKey[key] = true
+		quotedKeys = append(quotedKeys, quote(key))
+	}
+
+	quotedNames := make([]string, 0, len(names))
+	updates := make([]string, 0, len(names))
+
+	for _, name := range names {
+		quotedNames = append(quotedNames, quote(name))
+
+		if !isKey[name] {
+			updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", quote(name), quote(name)))
+		}
+	}
+
+	conflict := "DO NOTHING"
+	if len(updates) > 0 {
+		conflict = "DO UPDATE SET " + strings.Join(updates, ", ")
+	}
+
+	cols := strings.Join(quotedNames, ", ")
+
+	keyList := strings.Join(quotedKeys, ", ")
+
+	// DISTINCT ON keeps a single row per key, as ON CONFLICT cannot touch
+	// the same row twice in one statement. Of rows sharing a key the last
+	// one in the report wins, as it would with one upsert per row.
+	return fmt.Sprintf(
+		"INSERT INTO %s (%s) SELECT DISTINCT ON (%s) %s FROM %s ORDER BY %s, %s DESC ON CONFLICT (%s) %s",
+		table, cols, keyList, cols, staging, keyList, quote(stagingRowColumn), keyList, conflict,
+	)
+}
+
+func columnDef(col gosmartis.ColumnInfo, notNull bool) string {
+	def := quote(col.ID) + " " + sqlType(col.Kind)
+	if notNull {
+		def += " NOT NULL"
+	}
+
+	return def
+}
+
+func sqlType(kind gosmartis.ColumnKind) string {
+	switch kind {
+	case gosmartis.ColumnInt:
+		return "bigint"
+	case gosmartis.ColumnFloat:
+		return "double precision"
+	case gosmartis.ColumnBool:
+		return "boolean"
+	case gosmartis.ColumnDate:
+		return "date"
+	case gosmartis.ColumnDateTime:
+		return "timestamp"
+	default:
+		return "text"
+	}
+}
+
+func columnKind(sqlType string) gosmartis.ColumnKind {
+	switch sqlType {
+	case "bigint", "integer", "smallint":
+		return gosmartis.ColumnInt
+	case "double precision", "real", "numeric":
+		return gosmartis.ColumnFloat
+	case "boolean":
+		return gosmartis.ColumnBool
+	case "date":
+		return gosmartis.ColumnDate
+	case "timestamp without time zone", "timestamp with time zone":
+		return gosmartis.ColumnDateTime
+	default:
+		return gosmartis.ColumnString
+	}
+}
+
+var errNullKey = errors.New("key column is null")
+
+// ErrKeyMismatch is returned when a report's key columns differ from the
+// key columns of the table it is loaded into.
+var ErrKeyMismatch = errors.New("key columns do not match the table")
+
+// copyRows converts the report rows to staging rows: the metric, the
+// columns and the row number for stagingRowColumn.
+func copyRows(report *gosmartis.Report, columns []gosmartis.ColumnInfo, keys []string) ([][]interface{}, error) {
+	index := make(map[string]int, len(columns))
+	for i, col := range columns {
+		index[col.ID] = i + 1
+	}
+
+	keyIndex := make([]int, 0, len(keys))
+	for _, key := range keys[1:] {
+		keyIndex = append(keyIndex, index[key])
+	}
+
+	rows := make([][]interface{}, 0, len(report.RowsMassive))
+
+	for n, row := range report.RowsMassive {
+		values := make([]interface{}, len(columns)+2)
+		values[0] = report.Metric
+		values[len(values)-1] = int64(n)
+
+		for _, cell := range row {
+			i, ok := index[cell.ColumnID]
+			if !ok || cell.Value == nil {
+				continue
+			}
+
+			value, err := convert(columns[i-1], cell.Value)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %w", n, err)
+			}
+
+			values[i] = value
+		}
+
+		for _, i := range keyIndex {
+			if values[i] == nil {
+				return nil, fmt.Errorf("row %d: %s: %w", n, columns[i-1].ID, errNullKey)
+			}
+		}
+
+		rows = append(rows, values)
+	}
+
+	return rows, nil
+}
+
+func convert(col gosmartis.ColumnInfo, value interface{}) (interface{}, error) {
+	var (
+		result interface{}
+		ok     bool
+	)
+
+	switch col.Kind {
+	case gosmartis.ColumnInt:
+		result, ok = gosmartis.CellInt(value)
+	case gosmartis.ColumnFloat:
+		result, ok = gosmartis.CellFloat(value)
+	case gosmartis.ColumnBool:
+		result, ok = value.(bool)
+	case gosmartis.ColumnDate:
+		result, ok = timeValue(value, gosmartis.ParseDate)
+	case gosmartis.ColumnDateTime:
+		result, ok = timeValue(value, gosmartis.ParseDateTime)
+	default:
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case float64:
+			return strconv.FormatFloat(v, 'f', -1, 64), nil
+		default:
+			return fmt.Sprint(v), nil
+		}
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("column %s: cannot store %T value %v as %s", col.ID, value, value, col.Kind)
+	}
+
+	return result, nil
+}
+
+func timeValue(value interface{}, parse func(string) (time.Time, bool)) (time.Time, bool) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, true
+	case string:
+		return parse(v)
+	default:
+		return time.Time{}, false
+	}
+}
+
+func quote(name string) string {
+	return pgx.Identifier{name}.Sanitize()
+}
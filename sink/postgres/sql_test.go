@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/zfullio/gosmartis"
+)
+
+func TestUpsertSQL(t *testing.T) {
+	got := upsertSQL(`"leads"`, `"staging"`, []string{"metric", "day", "leads"}, []string{"metric", "day"})
+	want := `INSERT INTO "leads" ("metric", "day", "leads") SELECT DISTINCT ON ("metric", "day") "metric", "day", "leads" ` +
+		`FROM "staging" ORDER BY "metric", "day", "gosmartis_staging_row" DESC ` +
+		`ON CONFLICT ("metric", "day") DO UPDATE SET "leads" = EXCLUDED."leads"`
+
+	if got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+
+	got = upsertSQL(`"t"`, `"s"`, []string{"metric", "day"}, []string{"metric", "day"})
+	if want := `INSERT INTO "t" ("metric", "day") SELECT DISTINCT ON ("metric", "day") "metric", "day" FROM "s" ` +
+		`ORDER BY "metric", "day", "gosmartis_staging_row" DESC ON CONFLICT ("metric", "day") DO NOTHING`; got != want {
+		t.Errorf("keys only: got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCopyRowsNumbersRows(t *testing.T) {
+	report := &gosmartis.Report{Metric: "leads", RowsMassive: []gosmartis.Row{
+		{gosmartis.NewCell("day", "2024-01-01", ""), gosmartis.NewCell("leads", 1.0, "")},
+		{gosmartis.NewCell("day", "2024-01-01", ""), gosmartis.NewCell("leads", 2.0, "")},
+	}}
+
+	columns := report.ColumnInfos()
+
+	rows, err := copyRows(report, columns, []string{MetricColumn, "day"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, row := range rows {
+		if len(row) != len(columns)+2 || row[0] != "leads" || row[len(row)-1] != int64(i) {
+			t.Errorf("row %d = %v", i, row)
+		}
+	}
+}
+
+func TestCopyRowsNullKey(t *testing.T) {
+	report := &gosmartis.Report{Metric: "leads", RowsMassive: []gosmartis.Row{
+		{gosmartis.NewCell("day", "2024-01-01", ""), gosmartis.NewCell("leads", 1.0, "")},
+		{gosmartis.NewCell("leads", 2.0, "")},
+	}}
+
+	columns := report.ColumnInfos()
+
+	_, err := copyRows(report, columns, []string{MetricColumn, "day"})
+	if err == nil {
+		t.Fatal("want an error for the row without day")
+	}
+}
+
+func TestSameKeys(t *testing.T) {
+	if !sameKeys([]string{"metric", "day", "ad_id"}, []string{"metric", "ad_id", "day"}) {
+		t.Error("same keys in another order do not match")
+	}
+
+	if sameKeys([]string{"metric", "day"}, []string{"metric", "ad_id"}) || sameKeys([]string{"metric"}, []string{"metric", "day"}) {
+		t.Error("different keys match")
+	}
+}
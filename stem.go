@@ -0,0 +1,83 @@
+package gosmartis
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Stem reduces a Russian or English word to a crude stem by stripping
+// common inflectional endings, so that "купить", "купит" and "купил"
+// or "buying" and "buys" fall together. It is meant for grouping search
+// queries, not for linguistics: stems of short words are left unchanged.
+func Stem(word string) string {
+	word = strings.ToLower(strings.TrimSpace(word))
+	word = strings.ReplaceAll(word, "ё", "е")
+
+	if isCyrillic(word) {
+		return stripSuffix(word, russianSuffixes, 3)
+	}
+
+	return stemEnglish(word)
+}
+
+// russianSuffixes are noun, adjective, verb and participle endings,
+// longest first.
+var russianSuffixes = []string{
+	"ующими", "ивания", "ывания",
+	"иями", "ться", "ется", "ются", "ение", "ания",
+	"ями", "ами", "иях", "ого", "его", "ому", "ему", "ыми", "ими",
+	"ешь", "ишь", "ете", "ите", "ает", "яет", "ует", "ают", "яют", "уют",
+	"ала", "яла", "ила", "ыла", "ели", "али", "или", "ать", "ять", "ить",
+	"еть", "уть",
+	"ах", "ях", "ов", "ев", "ей", "ий", "ый", "ой", "ая", "яя", "ое", "ее",
+	"ые", "ие", "ую", "юю", "ом", "ем", "ам", "ям", "ит", "ет", "ут", "ют",
+	"ат", "ят", "ал", "ял", "ил", "ыл", "ла", "ли", "ть", "ся",
+	"а", "я", "о", "е", "ы", "и", "у", "ю", "ь", "й",
+}
+
+// englishSuffixes are English endings, longest first.
+var englishSuffixes = []string{
+	"ational", "ization", "fulness", "ousness", "iveness",
+	"ings", "edly", "ness", "able", "ible",
+	"ing", "ers", "ies", "ied",
+	"ed", "er", "es", "ly", "s",
+}
+
+func stemEnglish(word string) string {
+	if strings.HasSuffix(word, "ss") {
+		return word
+	}
+
+	stem := stripSuffix(word, englishSuffixes, 3)
+
+	if stem+"ies" == word || stem+"ied" == word {
+		stem += "y"
+	}
+
+	return stem
+}
+
+// stripSuffix removes the first matching suffix that leaves at least min
+// runes.
+func stripSuffix(word string, suffixes []string, min int) string {
+	length := utf8.RuneCountInString(word)
+
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(word, suffix) && length-utf8.RuneCountInString(suffix) >= min {
+			return strings.TrimSuffix(word, suffix)
+		}
+	}
+
+	return word
+}
+
+func isCyrillic(word string) bool {
+	for _, r := range word {
+		if unicode.Is(unicode.Cyrillic, r) {
+			return true
+		}
+	}
+
+	return false
+}
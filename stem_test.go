@@ -0,0 +1,56 @@
+package gosmartis
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestStem(t *testing.T) {
+	groups := [][]string{
+		{"купить", "купит", "купил", "Купила"},
+		{"диван", "диваны", "диванов"},
+		{"кожаный", "кожаные"},
+		{"ёлка", "елки"},
+		{"buying", "buys", "buy"},
+		{"companies", "company"},
+		{"copied", "copy"},
+		{"glasses", "glass"},
+	}
+
+	for _, group := range groups {
+		want := Stem(group[0])
+
+		for _, word := range group[1:] {
+			if got := Stem(word); got != want {
+				t.Errorf("Stem(%q) = %q, want %q like %q", word, got, want, group[0])
+			}
+		}
+	}
+
+	tests := map[string]string{
+		"купить":    "куп",
+		"кожаный":   "кожан",
+		"companies": "company",
+		"class":     "class",
+		"дом":       "дом",
+		"run":       "run",
+		"in":        "in",
+		" Диван ":   "диван",
+	}
+
+	for word, want := range tests {
+		if got := Stem(word); got != want {
+			t.Errorf("Stem(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
+
+func TestSuffixesLongestFirst(t *testing.T) {
+	for name, suffixes := range map[string][]string{"russian": russianSuffixes, "english": englishSuffixes} {
+		for i := 1; i < len(suffixes); i++ {
+			if utf8.RuneCountInString(suffixes[i]) > utf8.RuneCountInString(suffixes[i-1]) {
+				t.Errorf("%s suffix %q is listed after the shorter %q", name, suffixes[i], suffixes[i-1])
+			}
+		}
+	}
+}
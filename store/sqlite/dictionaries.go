@@ -0,0 +1,204 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/zfullio/gosmartis"
+)
+
+func (s *Store) SaveChannels(ctx context.Context, channels []gosmartis.Channel) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, c := range channels {
+			_, err := tx.ExecContext(ctx,
+				`INSERT INTO channels (id, title, name, parent_channel_id, category_title, is_active)
+				VALUES (?, ?, ?, ?, ?, ?)
+				ON CONFLICT (id) DO UPDATE SET
+					title = excluded.title,
+					name = excluded.name,
+					parent_channel_id = excluded.parent_channel_id,
+					category_title = excluded.category_title,
+					is_active = excluded.is_active`,
+				c.ID, c.Title, c.Name, nullableID(int(c.ParentChannelID)), c.CategoryTitle, bool(c.IsActive),
+			)
+			if err != nil {
+				return fmt.Errorf("sqlite: save channel %d: %w", c.ID, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *Store) SavePlacements(ctx context.Context, placements []gosmartis.Placement) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, p := range placements {
+			_, err := tx.ExecContext(ctx,
+				`INSERT INTO placements (id, channel_id, title, name, is_active)
+				VALUES (?, ?, ?, ?, ?)
+				ON CONFLICT (id) DO UPDATE SET
+					channel_id = excluded.channel_id,
+					title = excluded.title,
+					name = excluded.name,
+					is_active = excluded.is_active`,
+				p.ID, nullableID(p.ChannelID), p.Title, p.Name, bool(p.IsActive),
+			)
+			if err != nil {
+				return fmt.Errorf("sqlite: save placement %d: %w", p.ID, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *Store) SaveCampaigns(ctx context.Context, campaigns []gosmartis.Campaign) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, c := range campaigns {
+			_, err := tx.ExecContext(ctx,
+				`INSERT INTO campaigns (id, placement_id, title)
+				VALUES (?, ?, ?)
+				ON CONFLICT (id) DO UPDATE SET
+					placement_id = excluded.placement_id,
+					title = excluded.title`,
+				c.Id, nullableID(c.PlacementId), c.Title,
+			)
+			if err != nil {
+				return fmt.Errorf("sqlite: save campaign %d: %w", c.Id, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *Store) SaveAds(ctx context.Context, ads []gosmartis.Ad) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, a := range ads {
+			_, err := tx.ExecContext(ctx,
+				`INSERT INTO ads (id, placement_id, campaign_id, external_id, type, title, text, href)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+				ON CONFLICT (id) DO UPDATE SET
+					placement_id = excluded.placement_id,
+					campaign_id = excluded.campaign_id,
+					external_id = excluded.external_id,
+					type = excluded.type,
+					title = excluded.title,
+					text = excluded.text,
+					href = excluded.href`,
+				a.ID, nullableID(a.PlacementID), nullableID(a.CampaignID), a.ExternalID, a.Type, a.Title, a.Text, nullableText(a.Href.Text()),
+			)
+			if err != nil {
+				return fmt.Errorf("sqlite: save ad %d: %w", a.ID, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// RefreshDictionaries fetches channels and placements, and the campaigns
+// and ads referenced by stored report rows, and saves them.
+func (s *Store) RefreshDictionaries(ctx context.Context, api gosmartis.API) error {
+	channels, err := api.GetChannels(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = s.SaveChannels(ctx, channels)
+	if err != nil {
+		return err
+	}
+
+	placements, err := api.GetPlacements(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = s.SavePlacements(ctx, placements)
+	if err != nil {
+		return err
+	}
+
+	campaignIDs, err := s.referencedIDs(ctx, "campaign_id")
+	if err != nil {
+		return err
+	}
+
+	if len(campaignIDs) > 0 {
+		campaigns, err := api.GetCampaigns(ctx, campaignIDs)
+		if err != nil {
+			return err
+		}
+
+		err = s.SaveCampaigns(ctx, campaigns)
+		if err != nil {
+			return err
+		}
+	}
+
+	adIDs, err := s.referencedIDs(ctx, "ad_id")
+	if err != nil {
+		return err
+	}
+
+	if len(adIDs) > 0 {
+		ads, err := api.GetAds(ctx, adIDs)
+		if err != nil {
+			return err
+		}
+
+		err = s.SaveAds(ctx, ads)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// referencedIDs lists distinct non-null values of a report_rows entity column.
+func (s *Store) referencedIDs(ctx context.Context, column string) ([]int, error) {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(
+		"SELECT DISTINCT %[1]s FROM report_rows WHERE %[1]s IS NOT NULL ORDER BY %[1]s", column,
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var ids []int
+
+	for rows.Next() {
+		var id int
+
+		err = rows.Scan(&id)
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
+// nullableID stores Smartis' zero "no entity" IDs as NULL.
+func nullableID(id int) interface{} {
+	if id == 0 {
+		return nil
+	}
+
+	return id
+}
+
+// nullableText stores empty strings as NULL.
+func nullableText(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+
+	return s
+}
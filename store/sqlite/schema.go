@@ -0,0 +1,99 @@
+package sqlite
+
+// schema is applied on every Open; all statements are idempotent.
+const schema = `
+CREATE TABLE IF NOT EXISTS snapshots (
+	id          INTEGER PRIMARY KEY,
+	metric      TEXT NOT NULL,
+	project     TEXT NOT NULL,
+	date_from   TEXT NOT NULL,
+	date_to     TEXT NOT NULL,
+	group_by    TEXT NOT NULL,
+	type        TEXT NOT NULL,
+	model_id    INTEGER NOT NULL,
+	payload     TEXT NOT NULL,
+	fetched_at  TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS snapshots_lookup ON snapshots (project, metric, date_from, date_to);
+
+CREATE TABLE IF NOT EXISTS snapshot_columns (
+	snapshot_id INTEGER NOT NULL REFERENCES snapshots (id) ON DELETE CASCADE,
+	column_id   TEXT NOT NULL,
+	name        TEXT NOT NULL,
+	cell_type   TEXT NOT NULL,
+	PRIMARY KEY (snapshot_id, column_id)
+);
+
+CREATE TABLE IF NOT EXISTS report_rows (
+	snapshot_id  INTEGER NOT NULL REFERENCES snapshots (id) ON DELETE CASCADE,
+	row_num      INTEGER NOT NULL,
+	day          TEXT,
+	channel_id   INTEGER,
+	placement_id INTEGER,
+	campaign_id  INTEGER,
+	ad_id        INTEGER,
+	data         TEXT NOT NULL,
+	PRIMARY KEY (snapshot_id, row_num)
+);
+
+CREATE TABLE IF NOT EXISTS channels (
+	id                INTEGER PRIMARY KEY,
+	title             TEXT NOT NULL,
+	name              TEXT,
+	parent_channel_id INTEGER,
+	category_title    TEXT,
+	is_active         INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS placements (
+	id         INTEGER PRIMARY KEY,
+	channel_id INTEGER,
+	title      TEXT NOT NULL,
+	name       TEXT,
+	is_active  INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS campaigns (
+	id           INTEGER PRIMARY KEY,
+	placement_id INTEGER,
+	title        TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS ads (
+	id           INTEGER PRIMARY KEY,
+	placement_id INTEGER,
+	campaign_id  INTEGER,
+	external_id  TEXT NOT NULL,
+	type         TEXT NOT NULL,
+	title        TEXT NOT NULL,
+	text         TEXT NOT NULL,
+	href         TEXT
+);
+
+DROP VIEW IF EXISTS report_rows_named;
+
+CREATE VIEW report_rows_named AS
+SELECT
+	s.id          AS snapshot_id,
+	s.project     AS project,
+	s.metric      AS metric,
+	s.fetched_at  AS fetched_at,
+	r.row_num     AS row_num,
+	r.day         AS day,
+	COALESCE(r.channel_id, p.channel_id)       AS channel_id,
+	ch.title      AS channel_title,
+	COALESCE(r.placement_id, a.placement_id, c.placement_id) AS placement_id,
+	p.title       AS placement_title,
+	COALESCE(r.campaign_id, a.campaign_id)     AS campaign_id,
+	c.title       AS campaign_title,
+	r.ad_id       AS ad_id,
+	a.title       AS ad_title,
+	r.data        AS data
+FROM report_rows r
+JOIN snapshots s ON s.id = r.snapshot_id
+LEFT JOIN ads a ON a.id = r.ad_id
+LEFT JOIN campaigns c ON c.id = COALESCE(r.campaign_id, a.campaign_id)
+LEFT JOIN placements p ON p.id = COALESCE(r.placement_id, a.placement_id, c.placement_id)
+LEFT JOIN channels ch ON ch.id = COALESCE(r.channel_id, p.channel_id);
+`
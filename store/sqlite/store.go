@@ -0,0 +1,335 @@
+// Package sqlite keeps local snapshots of Smartis reports in an embedded
+// SQLite database, so that analysts can fetch a period once and query it
+// offline.
+//
+// Every saved Report becomes a snapshot carrying its Payload, metric and
+// fetch time. Channels, placements, campaigns and ads are kept in
+// normalized tables, and the report_rows_named view joins report rows to
+// their entity titles. The data column holds the row as a JSON object keyed
+// by column ID, so a metric's value is under its code:
+//
+//	SELECT day, campaign_title, json_extract(data, '$.leads') AS leads
+//	FROM report_rows_named
+//	WHERE metric = 'leads'
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/zfullio/gosmartis"
+)
+
+const dateLayout = "2006-01-02"
+
+// Report columns copied into dedicated report_rows columns for joins.
+var entityColumns = map[string]string{
+	"day":          "day",
+	"channel_id":   "channel_id",
+	"placement_id": "placement_id",
+	"campaign_id":  "campaign_id",
+	"campaigns":    "campaign_id",
+	"ad_id":        "ad_id",
+}
+
+type Store struct {
+	db *sql.DB
+}
+
+// Open opens or creates the database file at path.
+func Open(path string) (*Store, error) {
+	db, err := sql.Open("sqlite", dsn(path))
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := New(db)
+	if err != nil {
+		db.Close()
+
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// dsn returns the URI for the database file at path. The path is escaped,
+// so "?", "#" and "%" in file names do not end up in the query.
+func dsn(path string) string {
+	uri := url.URL{
+		Scheme:   "file",
+		Opaque:   (&url.URL{Path: path}).EscapedPath(),
+		RawQuery: "_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)",
+	}
+
+	return uri.String()
+}
+
+// New wraps an open database and applies the schema.
+func New(db *sql.DB) (*Store, error) {
+	_, err := db.Exec(schema)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite: apply schema: %w", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// DB returns the underlying database for ad-hoc queries.
+func (s *Store) DB() *sql.DB {
+	return s.db
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Snapshot describes a saved report.
+type Snapshot struct {
+	ID        int64
+	Metric    string
+	Payload   gosmartis.Payload
+	FetchedAt time.Time
+}
+
+// SaveReports stores every report as a snapshot of payload fetched at
+// fetchedAt and returns the snapshot IDs in the order of reports.
+func (s *Store) SaveReports(ctx context.Context, payload gosmartis.Payload, fetchedAt time.Time, reports ...*gosmartis.Report) ([]int64, error) {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, 0, len(reports))
+
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		for _, report := range reports {
+			id, err := saveReport(ctx, tx, payload, payloadJSON, fetchedAt, report)
+			if err != nil {
+				return fmt.Errorf("sqlite: save %s: %w", report.Metric, err)
+			}
+
+			ids = append(ids, id)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+func saveReport(ctx context.Context, tx *sql.Tx, payload gosmartis.Payload, payloadJSON []byte, fetchedAt time.Time, report *gosmartis.Report) (int64, error) {
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO snapshots (metric, project, date_from, date_to, group_by, type, model_id, payload, fetched_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		report.Metric,
+		payload.Project,
+		payload.DateTimeFrom.Format(dateLayout),
+		payload.DateTimeTo.Format(dateLayout),
+		string(payload.GroupBy),
+		string(payload.TypeReport),
+		int(payload.Attribution.ModelID),
+		string(payloadJSON),
+		fetchedAt.UTC(),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, col := range report.ColumnInfos() {
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO snapshot_columns (snapshot_id, column_id, name, cell_type) VALUES (?, ?, ?, ?)",
+			id, col.ID, col.Name, string(col.Type),
+		)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	stmt, err := tx.PrepareContext(ctx,
+		`INSERT INTO report_rows (snapshot_id, row_num, day, channel_id, placement_id, campaign_id, ad_id, data)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	defer stmt.Close()
+
+	for n, row := range report.RowsMassive {
+		data := make(map[string]interface{}, len(row))
+		entities := make(map[string]interface{}, 5)
+
+		for _, cell := range row {
+			data[cell.ColumnID] = cell.Value
+
+			column, ok := entityColumns[cell.ColumnID]
+			if !ok || cell.Value == nil {
+				continue
+			}
+
+			if column == "day" {
+				entities[column] = cell.Value
+			} else if id, ok := gosmartis.CellInt(cell.Value); ok {
+				entities[column] = id
+			}
+		}
+
+		dataJSON, err := json.Marshal(data)
+		if err != nil {
+			return 0, err
+		}
+
+		_, err = stmt.ExecContext(ctx,
+			id, n,
+			entities["day"], entities["channel_id"], entities["placement_id"], entities["campaign_id"], entities["ad_id"],
+			string(dataJSON),
+		)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return id, nil
+}
+
+// Snapshots lists saved snapshots of a project, newest first.
+// An empty metric matches every metric.
+func (s *Store) Snapshots(ctx context.Context, project, metric string) ([]Snapshot, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, metric, payload, fetched_at FROM snapshots
+		WHERE project = ? AND (? = '' OR metric = ?)
+		ORDER BY fetched_at DESC, id DESC`,
+		project, metric, metric,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var snapshots []Snapshot
+
+	for rows.Next() {
+		var (
+			snapshot Snapshot
+			payload  string
+		)
+
+		err = rows.Scan(&snapshot.ID, &snapshot.Metric, &payload, &snapshot.FetchedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		err = json.Unmarshal([]byte(payload), &snapshot.Payload)
+		if err != nil {
+			return nil, err
+		}
+
+		snapshots = append(snapshots, snapshot)
+	}
+
+	return snapshots, rows.Err()
+}
+
+// LoadReport rebuilds the report saved as the given snapshot.
+func (s *Store) LoadReport(ctx context.Context, snapshotID int64) (*gosmartis.Report, error) {
+	report := gosmartis.Report{}
+
+	err := s.db.QueryRowContext(ctx, "SELECT metric FROM snapshots WHERE id = ?", snapshotID).Scan(&report.Metric)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[string]string)
+
+	colRows, err := s.db.QueryContext(ctx, "SELECT column_id, name FROM snapshot_columns WHERE snapshot_id = ?", snapshotID)
+	if err != nil {
+		return nil, err
+	}
+
+	for colRows.Next() {
+		var id, name string
+
+		err = colRows.Scan(&id, &name)
+		if err != nil {
+			colRows.Close()
+
+			return nil, err
+		}
+
+		names[id] = name
+	}
+
+	colRows.Close()
+
+	rows, err := s.db.QueryContext(ctx, "SELECT data FROM report_rows WHERE snapshot_id = ? ORDER BY row_num", snapshotID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var data string
+
+		err = rows.Scan(&data)
+		if err != nil {
+			return nil, err
+		}
+
+		var values map[string]interface{}
+
+		err = json.Unmarshal([]byte(data), &values)
+		if err != nil {
+			return nil, err
+		}
+
+		row := make(gosmartis.Row, 0, len(values))
+
+		for id, value := range values {
+			row = append(row, gosmartis.NewCell(id, value, names[id]))
+		}
+
+		report.RowsMassive = append(report.RowsMassive, row)
+	}
+
+	return &report, rows.Err()
+}
+
+// DeleteSnapshot removes a snapshot with its rows.
+func (s *Store) DeleteSnapshot(ctx context.Context, snapshotID int64) error {
+	_, err := s.db.ExecContext(ctx, "DELETE FROM snapshots WHERE id = ?", snapshotID)
+
+	return err
+}
+
+func (s *Store) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	err = fn(tx)
+	if err != nil {
+		_ = tx.Rollback()
+
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,269 @@
+package sqlite_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/zfullio/gosmartis"
+	"github.com/zfullio/gosmartis/mock"
+	"github.com/zfullio/gosmartis/store/sqlite"
+)
+
+func open(t *testing.T) *sqlite.Store {
+	t.Helper()
+
+	store, err := sqlite.Open(filepath.Join(t.TempDir(), "reports.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { store.Close() })
+
+	return store
+}
+
+func testPayload() gosmartis.Payload {
+	return gosmartis.Payload{
+		Project:      "object_1",
+		Metrics:      []string{"leads"},
+		DateTimeFrom: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		DateTimeTo:   time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+		GroupBy:      gosmartis.GroupByAd,
+		TypeReport:   gosmartis.TypeReportAggregated,
+		Attribution:  gosmartis.Attribution{ModelID: gosmartis.AttributionModelLastClick, Period: 30},
+	}
+}
+
+func testReport() *gosmartis.Report {
+	return &gosmartis.Report{Metric: "leads", RowsMassive: []gosmartis.Row{
+		{
+			gosmartis.NewCell("day", "2024-01-01", ""),
+			gosmartis.NewCell("ad_id", 11.0, ""),
+			gosmartis.NewCell("leads", 3.0, ""),
+			gosmartis.NewCell("field_7", "web", "Source"),
+		},
+		{
+			gosmartis.NewCell("day", "2024-01-02", ""),
+			gosmartis.NewCell("campaign_id", "21", ""),
+			gosmartis.NewCell("leads", nil, ""),
+			gosmartis.NewCell("field_7", "phone", "Source"),
+		},
+	}}
+}
+
+// rowValues returns a row as a map of column ID to value.
+func rowValues(row gosmartis.Row) map[string]interface{} {
+	values := make(map[string]interface{}, len(row))
+	for _, cell := range row {
+		values[cell.ColumnID] = cell.Value
+	}
+
+	return values
+}
+
+func TestSaveAndLoadReport(t *testing.T) {
+	store := open(t)
+	ctx := context.Background()
+	payload := testPayload()
+	fetchedAt := time.Date(2024, 1, 3, 9, 15, 0, 0, time.FixedZone("MSK", 3*60*60))
+
+	ids, err := store.SaveReports(ctx, payload, fetchedAt, testReport(), &gosmartis.Report{Metric: "visits"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ids) != 2 {
+		t.Fatalf("ids = %v", ids)
+	}
+
+	snapshots, err := store.Snapshots(ctx, "object_1", "leads")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(snapshots) != 1 {
+		t.Fatalf("snapshots = %+v", snapshots)
+	}
+
+	snapshot := snapshots[0]
+	if snapshot.ID != ids[0] || snapshot.Metric != "leads" || !snapshot.FetchedAt.Equal(fetchedAt) {
+		t.Errorf("snapshot = %+v", snapshot)
+	}
+
+	if !reflect.DeepEqual(snapshot.Payload, payload) {
+		t.Errorf("payload = %+v, want %+v", snapshot.Payload, payload)
+	}
+
+	all, err := store.Snapshots(ctx, "object_1", "")
+	if err != nil || len(all) != 2 {
+		t.Errorf("all snapshots = %+v, %v", all, err)
+	}
+
+	report, err := store.LoadReport(ctx, ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := testReport()
+	if report.Metric != want.Metric || len(report.RowsMassive) != len(want.RowsMassive) {
+		t.Fatalf("report = %+v", report)
+	}
+
+	for i, row := range report.RowsMassive {
+		if got, want := rowValues(row), rowValues(want.RowsMassive[i]); !reflect.DeepEqual(got, want) {
+			t.Errorf("row %d = %v, want %v", i, got, want)
+		}
+
+		for _, cell := range row {
+			if cell.ColumnID == "field_7" && cell.Name != "Source" {
+				t.Errorf("row %d: field_7 name = %q", i, cell.Name)
+			}
+		}
+	}
+
+	err = store.DeleteSnapshot(ctx, ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var rows int
+
+	err = store.DB().QueryRowContext(ctx, "SELECT count(*) FROM report_rows").Scan(&rows)
+	if err != nil || rows != 0 {
+		t.Errorf("rows after delete = %d, %v", rows, err)
+	}
+}
+
+func TestRefreshDictionariesAndNamedView(t *testing.T) {
+	store := open(t)
+	ctx := context.Background()
+
+	_, err := store.SaveReports(ctx, testPayload(), time.Now(), testReport())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var campaignIDs, adIDs []int
+
+	api := &mock.APIMock{
+		GetChannelsFunc: func(context.Context) ([]gosmartis.Channel, error) {
+			return []gosmartis.Channel{{ID: 1, Title: "Контекст"}}, nil
+		},
+		GetPlacementsFunc: func(context.Context) ([]gosmartis.Placement, error) {
+			return []gosmartis.Placement{{ID: 2, Title: "Яндекс.Директ", ChannelID: 1}}, nil
+		},
+		GetCampaignsFunc: func(_ context.Context, ids []int) ([]gosmartis.Campaign, error) {
+			campaignIDs = ids
+
+			return []gosmartis.Campaign{{Id: 21, PlacementId: 2, Title: "Brand"}, {Id: 22, PlacementId: 2, Title: "Sale"}}, nil
+		},
+		GetAdsFunc: func(_ context.Context, ids []int) ([]gosmartis.Ad, error) {
+			adIDs = ids
+
+			return []gosmartis.Ad{{ID: 11, PlacementID: 2, CampaignID: 22, Title: "Summer ad"}}, nil
+		},
+	}
+
+	err = store.RefreshDictionaries(ctx, api)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(campaignIDs, []int{21}) || !reflect.DeepEqual(adIDs, []int{11}) {
+		t.Errorf("requested campaigns %v and ads %v, want the referenced [21] and [11]", campaignIDs, adIDs)
+	}
+
+	counts := map[string]int{"channels": 1, "placements": 1, "campaigns": 2, "ads": 1}
+	for table, want := range counts {
+		var n int
+
+		err = store.DB().QueryRowContext(ctx, "SELECT count(*) FROM "+table).Scan(&n)
+		if err != nil || n != want {
+			t.Errorf("%s rows = %d, %v, want %d", table, n, err, want)
+		}
+	}
+
+	rows, err := store.DB().QueryContext(ctx,
+		`SELECT day, channel_title, placement_title, campaign_title, COALESCE(ad_title, ''), COALESCE(json_extract(data, '$.leads'), 0)
+		FROM report_rows_named WHERE metric = 'leads' ORDER BY row_num`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer rows.Close()
+
+	var got []string
+
+	for rows.Next() {
+		var (
+			day, channel, placement, campaign, ad string
+			leads                                 float64
+		)
+
+		err = rows.Scan(&day, &channel, &placement, &campaign, &ad, &leads)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got = append(got, day+" "+channel+" "+placement+" "+campaign+" "+ad+" "+strconv.FormatFloat(leads, 'f', -1, 64))
+	}
+
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"2024-01-01 Контекст Яндекс.Директ Sale Summer ad 3",
+		"2024-01-02 Контекст Яндекс.Директ Brand  0",
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("named rows:\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestOpenEscapesPath(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a b?c#d%20e.db")
+
+	store, err := sqlite.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = store.SaveReports(context.Background(), testPayload(), time.Now(), testReport())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var foreignKeys int
+
+	err = store.DB().QueryRow("PRAGMA foreign_keys").Scan(&foreignKeys)
+	if err != nil || foreignKeys != 1 {
+		t.Errorf("foreign_keys = %d, %v; the pragmas were not applied", foreignKeys, err)
+	}
+
+	store.Close()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+
+	sort.Strings(names)
+
+	if len(names) == 0 || names[0] != "a b?c#d%20e.db" {
+		t.Errorf("files = %q, want the database at the given name", names)
+	}
+}
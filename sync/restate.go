@@ -0,0 +1,356 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	gosync "sync"
+
+	"github.com/zfullio/gosmartis"
+)
+
+// AttributionRefreshDays returns how many days back conversions attributed
+// with a may still be restated. Models that credit earlier touches move
+// conversions up to Attribution.Period days into the past; last-touch models
+// credit the conversion day itself and need no extra restatement.
+func AttributionRefreshDays(a gosmartis.Attribution) int {
+	switch a.ModelID {
+	case gosmartis.AttributionModelLastClick,
+		gosmartis.AttributionModelLastClickWithPostview,
+		gosmartis.AttributionModelLastCommunication:
+		return 0
+	default:
+		return a.Period
+	}
+}
+
+// refreshDays is the refresh window of def, widened to the attribution
+// period when def asks for it.
+func refreshDays(def Definition) int {
+	days := def.RefreshDays
+
+	if def.AttributionRefresh {
+		if n := AttributionRefreshDays(def.Payload.Attribution); n > days {
+			days = n
+		}
+	}
+
+	return days
+}
+
+// ReportStore keeps the last reports written for a window, so that a
+// re-fetch can be compared with them. Windows are aligned to the grid of
+// Definition.WindowDays, so a window is identified by its From day; the
+// last window of a run may grow on the next run.
+type ReportStore interface {
+	LoadReports(ctx context.Context, name string, window Window) ([]*gosmartis.Report, error)
+	SaveReports(ctx context.Context, name string, window Window, reports []*gosmartis.Report) error
+}
+
+// MemoryReportStore keeps reports in memory. The zero value is ready to use.
+type MemoryReportStore struct {
+	mu      gosync.Mutex
+	reports map[string][]*gosmartis.Report
+}
+
+func (m *MemoryReportStore) LoadReports(_ context.Context, name string, window Window) ([]*gosmartis.Report, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.reports[name+"/"+window.From.Format(dateLayout)], nil
+}
+
+func (m *MemoryReportStore) SaveReports(_ context.Context, name string, window Window, reports []*gosmartis.Report) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.reports == nil {
+		m.reports = make(map[string][]*gosmartis.Report)
+	}
+
+	m.reports[name+"/"+window.From.Format(dateLayout)] = reports
+
+	return nil
+}
+
+// FileReportStore keeps the reports of every window in its own JSON file
+// under a directory, so that restatements are detected across restarts.
+// Files are laid out as <dir>/<name>/<window from>.json and rewritten
+// atomically.
+type FileReportStore struct {
+	dir string
+	mu  gosync.Mutex
+}
+
+func NewFileReportStore(dir string) *FileReportStore {
+	return &FileReportStore{dir: dir}
+}
+
+type reportJSON struct {
+	Metric string                `json:"metric"`
+	Rows   []map[string]cellJSON `json:"rows"`
+}
+
+type cellJSON struct {
+	Value interface{} `json:"value"`
+	Name  string      `json:"name,omitempty"`
+}
+
+func (f *FileReportStore) LoadReports(_ context.Context, name string, window Window) ([]*gosmartis.Report, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := f.path(name, window)
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var stored []reportJSON
+
+	err = json.Unmarshal(data, &stored)
+	if err != nil {
+		return nil, fmt.Errorf("sync: decode report file %s: %w", path, err)
+	}
+
+	reports := make([]*gosmartis.Report, 0, len(stored))
+
+	for _, r := range stored {
+		report := &gosmartis.Report{Metric: r.Metric, RowsMassive: make([]gosmartis.Row, 0, len(r.Rows))}
+
+		for _, cells := range r.Rows {
+			row := make(gosmartis.Row, 0, len(cells))
+			for id, cell := range cells {
+				row = append(row, gosmartis.NewCell(id, cell.Value, cell.Name))
+			}
+
+			report.RowsMassive = append(report.RowsMassive, row)
+		}
+
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+func (f *FileReportStore) SaveReports(_ context.Context, name string, window Window, reports []*gosmartis.Report) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	stored := make([]reportJSON, 0, len(reports))
+
+	for _, report := range reports {
+		r := reportJSON{Metric: report.Metric, Rows: make([]map[string]cellJSON, 0, len(report.RowsMassive))}
+
+		for _, row := range report.RowsMassive {
+			cells := make(map[string]cellJSON, len(row))
+			for _, cell := range row {
+				cells[cell.ColumnID] = cellJSON{Value: cell.Value, Name: cell.Name}
+			}
+
+			r.Rows = append(r.Rows, cells)
+		}
+
+		stored = append(stored, r)
+	}
+
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+
+	path := f.path(name, window)
+
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(path, data)
+}
+
+func (f *FileReportStore) path(name string, window Window) string {
+	return filepath.Join(f.dir, url.PathEscape(name), window.From.Format(dateLayout)+".json")
+}
+
+type ChangeKind int
+
+const (
+	RowAdded ChangeKind = iota + 1
+	RowRemoved
+	RowChanged
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case RowAdded:
+		return "added"
+	case RowRemoved:
+		return "removed"
+	case RowChanged:
+		return "changed"
+	default:
+		return fmt.Sprintf("ChangeKind(%d)", int(k))
+	}
+}
+
+// RowChange is a row that differs between the stored and the new report.
+type RowChange struct {
+	Metric string
+	Kind   ChangeKind
+	// Key holds the dimension values identifying the row.
+	Key map[string]interface{}
+	Old gosmartis.Row
+	New gosmartis.Row
+}
+
+// MetricDelta summarizes how a numeric column of a metric moved.
+type MetricDelta struct {
+	Metric      string
+	Column      string
+	Old         float64
+	New         float64
+	Delta       float64
+	ChangedRows int
+}
+
+// Restatement is the difference between two fetches of a window.
+type Restatement struct {
+	Window  Window
+	Changes []RowChange
+	Deltas  []MetricDelta
+}
+
+// Empty reports whether nothing changed.
+func (r *Restatement) Empty() bool {
+	return len(r.Changes) == 0
+}
+
+// DetectRestatement compares reports of the same window metric by metric.
+// Rows are matched on their dimension columns.
+func DetectRestatement(window Window, old, new []*gosmartis.Report) *Restatement {
+	result := &Restatement{Window: window}
+
+	oldByMetric := byMetric(old)
+	newByMetric := byMetric(new)
+
+	metrics := make([]string, 0, len(oldByMetric)+len(newByMetric))
+	for metric := range oldByMetric {
+		metrics = append(metrics, metric)
+	}
+
+	for metric := range newByMetric {
+		if _, ok := oldByMetric[metric]; !ok {
+			metrics = append(metrics, metric)
+		}
+	}
+
+	sort.Strings(metrics)
+
+	for _, metric := range metrics {
+		changes, deltas := diffRows(metric, oldByMetric[metric], newByMetric[metric])
+		result.Changes = append(result.Changes, changes...)
+		result.Deltas = append(result.Deltas, deltas...)
+	}
+
+	return result
+}
+
+func byMetric(reports []*gosmartis.Report) map[string]*gosmartis.Report {
+	result := make(map[string]*gosmartis.Report, len(reports))
+
+	for _, report := range reports {
+		merged, ok := result[report.Metric]
+		if !ok {
+			merged = &gosmartis.Report{Metric: report.Metric}
+			result[report.Metric] = merged
+		}
+
+		merged.RowsMassive = append(merged.RowsMassive, report.RowsMassive...)
+	}
+
+	return result
+}
+
+var changeKinds = map[gosmartis.DiffKind]ChangeKind{
+	gosmartis.DiffAdded:   RowAdded,
+	gosmartis.DiffRemoved: RowRemoved,
+	gosmartis.DiffChanged: RowChanged,
+}
+
+func diffRows(metric string, old, new *gosmartis.Report) ([]RowChange, []MetricDelta) {
+	diff := gosmartis.Diff(old, new)
+
+	changes := make([]RowChange, 0, len(diff.Rows))
+	changedRows := make(map[string]int, len(diff.Totals))
+
+	for _, row := range diff.Rows {
+		changes = append(changes, RowChange{
+			Metric: metric,
+			Kind:   changeKinds[row.Kind],
+			Key:    row.Key,
+			Old:    row.Old,
+			New:    row.New,
+		})
+
+		for _, delta := range row.Deltas {
+			changedRows[delta.Column]++
+		}
+	}
+
+	deltas := make([]MetricDelta, 0, len(diff.Totals))
+	for _, total := range diff.Totals {
+		deltas = append(deltas, MetricDelta{
+			Metric:      metric,
+			Column:      total.Column,
+			Old:         total.Old,
+			New:         total.New,
+			Delta:       total.Abs,
+			ChangedRows: changedRows[total.Column],
+		})
+	}
+
+	return changes, deltas
+}
+
+// ChangeSink receives only what changed since the last fetch of a window.
+type ChangeSink interface {
+	WriteChanges(ctx context.Context, def Definition, restatement *Restatement) error
+}
+
+// RestatementSink is a Sink that compares every fetched window with the
+// reports stored for it, forwards the changes to Changes and then stores
+// the new reports. Windows without changes are not forwarded.
+type RestatementSink struct {
+	Store   ReportStore
+	Changes ChangeSink
+}
+
+func (s *RestatementSink) Write(ctx context.Context, def Definition, window Window, reports []*gosmartis.Report) error {
+	old, err := s.Store.LoadReports(ctx, def.Name, window)
+	if err != nil {
+		return err
+	}
+
+	restatement := DetectRestatement(window, old, reports)
+
+	if !restatement.Empty() {
+		err = s.Changes.WriteChanges(ctx, def, restatement)
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.Store.SaveReports(ctx, def.Name, window, reports)
+}
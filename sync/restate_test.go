@@ -0,0 +1,145 @@
+package sync
+
+import (
+	"context"
+	"testing"
+
+	"github.com/zfullio/gosmartis"
+)
+
+func leadsReport(leads ...float64) *gosmartis.Report {
+	report := &gosmartis.Report{Metric: "leads"}
+
+	for i, n := range leads {
+		report.RowsMassive = append(report.RowsMassive, gosmartis.Row{
+			gosmartis.NewCell("day", "2024-03-01", ""),
+			gosmartis.NewCell("ad_id", float64(i+1), ""),
+			gosmartis.NewCell("field_3", "site", "Source"),
+			gosmartis.NewCell("leads", n, ""),
+		})
+	}
+
+	return report
+}
+
+type changeRecorder struct {
+	restatements []*Restatement
+}
+
+func (c *changeRecorder) WriteChanges(_ context.Context, _ Definition, r *Restatement) error {
+	c.restatements = append(c.restatements, r)
+
+	return nil
+}
+
+func TestFileReportStoreSurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	def := Definition{Name: "leads/daily"}
+	window := Window{From: date("2024-03-01"), To: date("2024-03-01")}
+
+	first := &changeRecorder{}
+	sink := &RestatementSink{Store: NewFileReportStore(dir), Changes: first}
+
+	err := sink.Write(ctx, def, window, []*gosmartis.Report{leadsReport(1, 2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(first.restatements) != 1 || len(first.restatements[0].Changes) != 2 {
+		t.Fatalf("first write: %+v, want two added rows", first.restatements)
+	}
+
+	// A new store over the same directory sees the saved reports, so an
+	// unchanged re-fetch is not reported as new rows.
+	second := &changeRecorder{}
+	sink = &RestatementSink{Store: NewFileReportStore(dir), Changes: second}
+
+	err = sink.Write(ctx, def, window, []*gosmartis.Report{leadsReport(1, 2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(second.restatements) != 0 {
+		t.Fatalf("unchanged re-fetch after restart: %+v", second.restatements[0].Changes)
+	}
+
+	err = sink.Write(ctx, def, window, []*gosmartis.Report{leadsReport(1, 5)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(second.restatements) != 1 {
+		t.Fatalf("restatements = %d, want 1", len(second.restatements))
+	}
+
+	changes := second.restatements[0].Changes
+	if len(changes) != 1 || changes[0].Kind != RowChanged || changes[0].Key["ad_id"] != 2.0 {
+		t.Errorf("changes = %+v, want ad 2 changed", changes)
+	}
+}
+
+func TestFileReportStoreRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	store := NewFileReportStore(t.TempDir())
+	window := Window{From: date("2024-03-01"), To: date("2024-03-07")}
+
+	reports, err := store.LoadReports(ctx, "missing", window)
+	if err != nil || reports != nil {
+		t.Fatalf("missing window = %v, %v", reports, err)
+	}
+
+	err = store.SaveReports(ctx, "d", window, []*gosmartis.Report{leadsReport(3)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The last window of a run may grow; it is still found by its From day.
+	grown := Window{From: window.From, To: date("2024-03-09")}
+
+	reports, err = store.LoadReports(ctx, "d", grown)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(reports) != 1 || reports[0].Metric != "leads" || len(reports[0].RowsMassive) != 1 {
+		t.Fatalf("reports = %+v", reports)
+	}
+
+	row := reports[0].RowsMassive[0]
+	if row.Value("leads") != 3.0 || row.Value("day") != "2024-03-01" {
+		t.Errorf("row values = %v, %v", row.Value("leads"), row.Value("day"))
+	}
+
+	for _, cell := range row {
+		if cell.ColumnID == "field_3" && (cell.Name != "Source" || cell.Type != gosmartis.CellTypeField) {
+			t.Errorf("field cell = %+v, want name and field type kept", cell)
+		}
+	}
+}
+
+func TestDetectRestatementSumsDuplicateKeys(t *testing.T) {
+	window := Window{From: date("2024-03-01"), To: date("2024-03-01")}
+
+	split := func(parts ...float64) *gosmartis.Report {
+		report := &gosmartis.Report{Metric: "leads"}
+		for _, n := range parts {
+			report.RowsMassive = append(report.RowsMassive, gosmartis.Row{
+				gosmartis.NewCell("day", "2024-03-01", ""),
+				gosmartis.NewCell("leads", n, ""),
+			})
+		}
+
+		return report
+	}
+
+	r := DetectRestatement(window, []*gosmartis.Report{split(1, 2)}, []*gosmartis.Report{split(3)})
+	if !r.Empty() {
+		t.Errorf("same total split differently: %+v", r.Changes)
+	}
+
+	r = DetectRestatement(window, []*gosmartis.Report{split(1, 2)}, []*gosmartis.Report{split(1, 1)})
+	if len(r.Changes) != 1 || len(r.Deltas) != 1 || r.Deltas[0].Delta != -1 {
+		t.Errorf("restatement = %+v, want leads 3 -> 2", r)
+	}
+}
@@ -0,0 +1,177 @@
+package sync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	gosync "sync"
+	"time"
+)
+
+// State is the sync progress of a definition.
+type State struct {
+	// Watermark is the last day that has been fully synced.
+	Watermark time.Time
+	// LastRun is the time the definition last wrote a window.
+	LastRun time.Time
+}
+
+type stateJSON struct {
+	Watermark string    `json:"watermark,omitempty"`
+	LastRun   time.Time `json:"last_run"`
+}
+
+func (s State) MarshalJSON() ([]byte, error) {
+	data := stateJSON{LastRun: s.LastRun}
+	if !s.Watermark.IsZero() {
+		data.Watermark = s.Watermark.Format(dateLayout)
+	}
+
+	return json.Marshal(data)
+}
+
+func (s *State) UnmarshalJSON(b []byte) error {
+	var data stateJSON
+
+	err := json.Unmarshal(b, &data)
+	if err != nil {
+		return err
+	}
+
+	s.LastRun = data.LastRun
+	s.Watermark = time.Time{}
+
+	if data.Watermark != "" {
+		s.Watermark, err = time.Parse(dateLayout, data.Watermark)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// StateStore persists the State of definitions by name.
+type StateStore interface {
+	// Load returns the state of name and false if nothing has been saved yet.
+	Load(ctx context.Context, name string) (State, bool, error)
+	Save(ctx context.Context, name string, state State) error
+}
+
+// MemoryStore keeps states in memory. The zero value is ready to use.
+type MemoryStore struct {
+	mu     gosync.Mutex
+	states map[string]State
+}
+
+func (m *MemoryStore) Load(_ context.Context, name string) (State, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.states[name]
+
+	return state, ok, nil
+}
+
+func (m *MemoryStore) Save(_ context.Context, name string, state State) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.states == nil {
+		m.states = make(map[string]State)
+	}
+
+	m.states[name] = state
+
+	return nil
+}
+
+// FileStore keeps all states in a single JSON file. Every Save rewrites the
+// file atomically.
+type FileStore struct {
+	path string
+	mu   gosync.Mutex
+}
+
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+func (f *FileStore) Load(_ context.Context, name string) (State, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	states, err := f.read()
+	if err != nil {
+		return State{}, false, err
+	}
+
+	state, ok := states[name]
+
+	return state, ok, nil
+}
+
+func (f *FileStore) Save(_ context.Context, name string, state State) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	states, err := f.read()
+	if err != nil {
+		return err
+	}
+
+	states[name] = state
+
+	data, err := json.MarshalIndent(states, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(f.path, data)
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it over path, so readers never see a partial file.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func (f *FileStore) read() (map[string]State, error) {
+	states := make(map[string]State)
+
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return states, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(data, &states)
+	if err != nil {
+		return nil, fmt.Errorf("sync: decode state file %s: %w", f.path, err)
+	}
+
+	return states, nil
+}
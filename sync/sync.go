@@ -0,0 +1,260 @@
+// Package sync keeps report tables up to date with incremental fetches.
+//
+// An Engine takes report definitions, remembers per definition the last
+// fully synced day (the watermark) in a StateStore, and on every run fetches
+// the days after the watermark plus a refresh window of recent days whose
+// conversions may still change. Each fetched window is handed to a Sink and
+// the watermark is advanced right after, so an interrupted run resumes where
+// it stopped.
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/zfullio/gosmartis"
+)
+
+const dateLayout = "2006-01-02"
+
+// Definition describes a report to keep in sync.
+type Definition struct {
+	// Name identifies the definition in the state store.
+	Name string
+	// Payload is the report request. Its date range is set per window.
+	Payload gosmartis.Payload
+	// Start is the first day to fetch when there is no watermark yet.
+	Start time.Time
+	// RefreshDays is the number of days up to the watermark that are fetched
+	// again on every run to pick up late conversions.
+	RefreshDays int
+	// AttributionRefresh widens RefreshDays to the attribution period of
+	// multi-touch models, see AttributionRefreshDays.
+	AttributionRefresh bool
+	// WindowDays is the number of days per GetReport call. Windows are
+	// aligned to a fixed grid of WindowDays days counted from the Unix
+	// epoch, so a window is always fetched with the same bounds.
+	// Zero fetches one day per call.
+	WindowDays int
+}
+
+// Window is an inclusive range of days.
+type Window struct {
+	From time.Time
+	To   time.Time
+}
+
+func (w Window) String() string {
+	return w.From.Format(dateLayout) + ".." + w.To.Format(dateLayout)
+}
+
+// Days returns the number of days in the window.
+func (w Window) Days() int {
+	return int(w.To.Sub(w.From)/(24*time.Hour)) + 1
+}
+
+// Sink receives the reports of a fetched window. A window may be fetched
+// again later, so sinks should replace rather than append.
+type Sink interface {
+	Write(ctx context.Context, def Definition, window Window, reports []*gosmartis.Report) error
+}
+
+// SinkFunc adapts a function to Sink.
+type SinkFunc func(ctx context.Context, def Definition, window Window, reports []*gosmartis.Report) error
+
+func (f SinkFunc) Write(ctx context.Context, def Definition, window Window, reports []*gosmartis.Report) error {
+	return f(ctx, def, window, reports)
+}
+
+type Engine struct {
+	API   gosmartis.API
+	State StateStore
+	Sink  Sink
+	// Now returns the current time; defaults to time.Now.
+	Now func() time.Time
+	// IncludeToday also fetches the current, incomplete day. It is fetched on
+	// every run and never moves the watermark.
+	IncludeToday bool
+}
+
+// Plan returns the windows the next run of def will fetch.
+func (e *Engine) Plan(ctx context.Context, def Definition) ([]Window, error) {
+	state, _, err := e.State.Load(ctx, def.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.plan(def, state), nil
+}
+
+func (e *Engine) plan(def Definition, state State) []Window {
+	today := day(e.now())
+	last := today.AddDate(0, 0, -1)
+
+	if e.IncludeToday {
+		last = today
+	}
+
+	from := day(def.Start)
+
+	if !state.Watermark.IsZero() {
+		from = day(state.Watermark).AddDate(0, 0, 1)
+
+		if n := refreshDays(def); n > 0 {
+			refresh := day(state.Watermark).AddDate(0, 0, 1-n)
+			if refresh.Before(from) {
+				from = refresh
+			}
+		}
+
+		from = gridStart(from, def.WindowDays)
+
+		if from.Before(day(def.Start)) {
+			from = day(def.Start)
+		}
+	}
+
+	if from.IsZero() {
+		return nil
+	}
+
+	return split(Window{From: from, To: last}, def.WindowDays)
+}
+
+// split cuts w at the boundaries of the size days grid.
+func split(w Window, size int) []Window {
+	if size <= 0 {
+		size = 1
+	}
+
+	var windows []Window
+
+	for from := w.From; !from.After(w.To); {
+		to := gridStart(from, size).AddDate(0, 0, size-1)
+		if to.After(w.To) {
+			to = w.To
+		}
+
+		windows = append(windows, Window{From: from, To: to})
+		from = to.AddDate(0, 0, 1)
+	}
+
+	return windows
+}
+
+// gridStart returns the first day of the size days grid cell holding d.
+func gridStart(d time.Time, size int) time.Time {
+	if size <= 1 {
+		return d
+	}
+
+	days := int(d.Unix() / int64(24*time.Hour/time.Second))
+
+	offset := days % size
+	if offset < 0 {
+		offset += size
+	}
+
+	return d.AddDate(0, 0, -offset)
+}
+
+// Run syncs every definition. A failing definition does not stop the
+// others; all errors are returned joined.
+func (e *Engine) Run(ctx context.Context, defs ...Definition) error {
+	var errs []error
+
+	for _, def := range defs {
+		err := e.RunDefinition(ctx, def)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("sync %s: %w", def.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// RunDefinition syncs a single definition and stops at the first failing
+// window. Windows synced before the failure keep their progress.
+func (e *Engine) RunDefinition(ctx context.Context, def Definition) error {
+	if def.Name == "" {
+		return errors.New("definition name is empty")
+	}
+
+	state, _, err := e.State.Load(ctx, def.Name)
+	if err != nil {
+		return err
+	}
+
+	today := day(e.now())
+
+	for _, window := range e.plan(def, state) {
+		err = ctx.Err()
+		if err != nil {
+			return err
+		}
+
+		reports, err := e.fetch(ctx, def, window)
+		if err != nil {
+			return fmt.Errorf("fetch %s: %w", window, err)
+		}
+
+		err = e.Sink.Write(ctx, def, window, reports)
+		if err != nil {
+			return fmt.Errorf("write %s: %w", window, err)
+		}
+
+		state.LastRun = e.now()
+
+		// The current day is incomplete and must be fetched again.
+		complete := window.To
+		if !complete.Before(today) {
+			complete = today.AddDate(0, 0, -1)
+		}
+
+		if complete.After(state.Watermark) {
+			state.Watermark = complete
+		}
+
+		err = e.State.Save(ctx, def.Name, state)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *Engine) fetch(ctx context.Context, def Definition, window Window) ([]*gosmartis.Report, error) {
+	payload := def.Payload
+	payload.DateTimeFrom = window.From
+	payload.DateTimeTo = window.To
+
+	reports, err := e.API.GetReport(ctx, payload)
+	if errors.Is(err, gosmartis.ErrNoReportData) {
+		return nil, nil
+	}
+
+	return reports, err
+}
+
+func (e *Engine) now() time.Time {
+	if e.Now == nil {
+		return time.Now()
+	}
+
+	return e.Now()
+}
+
+// day returns the calendar day of t as midnight UTC, so that days taken
+// from different locations compare equal.
+func day(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+
+	y, m, d := t.Date()
+
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
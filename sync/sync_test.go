@@ -0,0 +1,250 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zfullio/gosmartis"
+	"github.com/zfullio/gosmartis/mock"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		panic(err)
+	}
+
+	return t
+}
+
+func windows(ws []Window) []string {
+	result := make([]string, 0, len(ws))
+	for _, w := range ws {
+		result = append(result, w.String())
+	}
+
+	return result
+}
+
+func TestPlan(t *testing.T) {
+	now := func() time.Time { return time.Date(2024, 3, 10, 15, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name      string
+		def       Definition
+		watermark string
+		today     bool
+		want      []string
+	}{
+		{
+			name: "first run",
+			def:  Definition{Start: date("2024-03-07")},
+			want: []string{"2024-03-07..2024-03-07", "2024-03-08..2024-03-08", "2024-03-09..2024-03-09"},
+		},
+		{
+			name:      "after watermark",
+			def:       Definition{Start: date("2024-03-01")},
+			watermark: "2024-03-08",
+			want:      []string{"2024-03-09..2024-03-09"},
+		},
+		{
+			name:      "refresh window",
+			def:       Definition{Start: date("2024-03-01"), RefreshDays: 3},
+			watermark: "2024-03-09",
+			want:      []string{"2024-03-07..2024-03-07", "2024-03-08..2024-03-08", "2024-03-09..2024-03-09"},
+		},
+		{
+			name:      "refresh does not go before start",
+			def:       Definition{Start: date("2024-03-08"), RefreshDays: 10},
+			watermark: "2024-03-09",
+			want:      []string{"2024-03-08..2024-03-08", "2024-03-09..2024-03-09"},
+		},
+		{
+			name:      "attribution refresh",
+			def:       Definition{Start: date("2024-01-01"), RefreshDays: 1, AttributionRefresh: true, Payload: gosmartis.Payload{Attribution: gosmartis.Attribution{ModelID: gosmartis.AttributionModelFirstClick, Period: 2}}},
+			watermark: "2024-03-09",
+			want:      []string{"2024-03-08..2024-03-08", "2024-03-09..2024-03-09"},
+		},
+		{
+			// 2024-02-29 is a multiple of 7 days after the epoch, a Thursday.
+			name:      "grid windows",
+			def:       Definition{Start: date("2024-02-01"), WindowDays: 7},
+			watermark: "2024-03-01",
+			want:      []string{"2024-02-29..2024-03-06", "2024-03-07..2024-03-09"},
+		},
+		{
+			name:  "include today",
+			def:   Definition{Start: date("2024-03-09")},
+			today: true,
+			want:  []string{"2024-03-09..2024-03-09", "2024-03-10..2024-03-10"},
+		},
+		{
+			name: "no start",
+			def:  Definition{},
+			want: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Engine{Now: now, IncludeToday: tt.today}
+
+			var state State
+			if tt.watermark != "" {
+				state.Watermark = date(tt.watermark)
+			}
+
+			got := windows(e.plan(tt.def, state))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("plan = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGridStart(t *testing.T) {
+	if got := gridStart(date("2024-03-06"), 7); !got.Equal(date("2024-02-29")) {
+		t.Errorf("gridStart = %s, want 2024-02-29", got.Format(dateLayout))
+	}
+
+	if got := gridStart(date("1969-12-30"), 7); got.Weekday() != time.Thursday {
+		t.Errorf("gridStart before the epoch = %s, not aligned to the epoch weekday", got.Format(dateLayout))
+	}
+}
+
+func TestRunDefinitionResumes(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)
+	failOn := date("2024-03-03")
+
+	var fetched []string
+
+	api := &mock.APIMock{
+		GetReportFunc: func(_ context.Context, p gosmartis.Payload) ([]*gosmartis.Report, error) {
+			fetched = append(fetched, p.DateTimeFrom.Format(dateLayout))
+
+			if p.DateTimeFrom.Equal(failOn) {
+				return nil, errors.New("boom")
+			}
+
+			if p.DateTimeFrom.Equal(date("2024-03-02")) {
+				return nil, gosmartis.ErrNoReportData
+			}
+
+			return []*gosmartis.Report{{Metric: "leads"}}, nil
+		},
+	}
+
+	var written []string
+
+	store := NewFileStore(filepath.Join(t.TempDir(), "state.json"))
+	engine := &Engine{
+		API:   api,
+		State: store,
+		Sink: SinkFunc(func(_ context.Context, _ Definition, w Window, reports []*gosmartis.Report) error {
+			written = append(written, w.String())
+
+			return nil
+		}),
+		Now: func() time.Time { return now },
+	}
+
+	def := Definition{Name: "leads", Start: date("2024-03-01")}
+
+	err := engine.RunDefinition(ctx, def)
+	if err == nil {
+		t.Fatal("want the fetch error")
+	}
+
+	state, ok, err := store.Load(ctx, "leads")
+	if err != nil || !ok {
+		t.Fatalf("Load = %v, %v, %v", state, ok, err)
+	}
+
+	if !state.Watermark.Equal(date("2024-03-02")) {
+		t.Errorf("watermark after failure = %s, want 2024-03-02", state.Watermark.Format(dateLayout))
+	}
+
+	failOn = time.Time{}
+	fetched = nil
+
+	err = engine.Run(ctx, def)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"2024-03-03", "2024-03-04"}; !reflect.DeepEqual(fetched, want) {
+		t.Errorf("resumed fetches = %v, want %v", fetched, want)
+	}
+
+	if want := []string{"2024-03-01..2024-03-01", "2024-03-02..2024-03-02", "2024-03-03..2024-03-03", "2024-03-04..2024-03-04"}; !reflect.DeepEqual(written, want) {
+		t.Errorf("written = %v, want %v", written, want)
+	}
+
+	state, _, _ = store.Load(ctx, "leads")
+	if !state.Watermark.Equal(date("2024-03-04")) || !state.LastRun.Equal(now) {
+		t.Errorf("state = %+v, want watermark 2024-03-04 and last run %s", state, now)
+	}
+}
+
+func TestIncludeTodayKeepsWatermark(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)
+	store := &MemoryStore{}
+
+	engine := &Engine{
+		API: &mock.APIMock{GetReportFunc: func(context.Context, gosmartis.Payload) ([]*gosmartis.Report, error) {
+			return nil, nil
+		}},
+		State:        store,
+		Sink:         SinkFunc(func(context.Context, Definition, Window, []*gosmartis.Report) error { return nil }),
+		Now:          func() time.Time { return now },
+		IncludeToday: true,
+	}
+
+	err := engine.RunDefinition(ctx, Definition{Name: "d", Start: date("2024-03-04")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state, _, _ := store.Load(ctx, "d")
+	if !state.Watermark.Equal(date("2024-03-04")) {
+		t.Errorf("watermark = %s, want yesterday 2024-03-04", state.Watermark.Format(dateLayout))
+	}
+}
+
+func TestStateJSON(t *testing.T) {
+	ctx := context.Background()
+	store := NewFileStore(filepath.Join(t.TempDir(), "state.json"))
+
+	_, ok, err := store.Load(ctx, "missing")
+	if err != nil || ok {
+		t.Fatalf("missing state: %v, %v", ok, err)
+	}
+
+	want := State{Watermark: date("2024-02-29"), LastRun: time.Date(2024, 3, 1, 1, 2, 3, 0, time.UTC)}
+
+	err = store.Save(ctx, "a", want)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = store.Save(ctx, "b", State{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok, err := store.Load(ctx, "a")
+	if err != nil || !ok || !got.Watermark.Equal(want.Watermark) || !got.LastRun.Equal(want.LastRun) {
+		t.Errorf("Load = %+v, %v, %v; want %+v", got, ok, err, want)
+	}
+
+	got, ok, _ = store.Load(ctx, "b")
+	if !ok || !got.Watermark.IsZero() {
+		t.Errorf("empty state = %+v, %v", got, ok)
+	}
+}
@@ -0,0 +1,232 @@
+// Package telemetry reports Smartis client calls as OpenTelemetry spans and
+// metrics.
+//
+//	obs, err := telemetry.New(telemetry.Options{})
+//	if err != nil {
+//		return err
+//	}
+//	client.Observer = obs
+//
+// Every Client method gets a span named after it, e.g. "smartis.GetReport",
+// with the project, metrics, date range and rows decoded; every HTTP request
+// gets a child client span with the endpoint, status code and body sizes.
+// A client without an Observer does no telemetry work at all.
+//
+// Retries are not recorded: the client sends every request once and has no
+// retry loop, so a call the caller repeats shows up as a separate span.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/zfullio/gosmartis"
+)
+
+const instrumentationName = "github.com/zfullio/gosmartis/telemetry"
+
+const dateLayout = "2006-01-02"
+
+const (
+	methodKey        = attribute.Key("smartis.method")
+	endpointKey      = attribute.Key("smartis.endpoint")
+	projectKey       = attribute.Key("smartis.project")
+	metricsKey       = attribute.Key("smartis.metrics")
+	metricCountKey   = attribute.Key("smartis.metrics.count")
+	dateFromKey      = attribute.Key("smartis.date_from")
+	dateToKey        = attribute.Key("smartis.date_to")
+	idsKey           = attribute.Key("smartis.ids.count")
+	rowsKey          = attribute.Key("smartis.rows")
+	noDataKey        = attribute.Key("smartis.no_data")
+	waitKey          = attribute.Key("smartis.limiter.wait_ms")
+	statusCodeKey    = attribute.Key("http.response.status_code")
+	requestBytesKey  = attribute.Key("http.request.body.size")
+	responseBytesKey = attribute.Key("http.response.body.size")
+	errorKey         = attribute.Key("error")
+)
+
+type Options struct {
+	// TracerProvider defaults to the global provider.
+	TracerProvider trace.TracerProvider
+	// MeterProvider defaults to the global provider.
+	MeterProvider metric.MeterProvider
+}
+
+// Observer implements gosmartis.Observer.
+type Observer struct {
+	tracer trace.Tracer
+
+	callDuration    metric.Float64Histogram
+	requestDuration metric.Float64Histogram
+	requestSize     metric.Int64Histogram
+	responseSize    metric.Int64Histogram
+	limiterWait     metric.Float64Histogram
+	requests        metric.Int64Counter
+	rows            metric.Int64Counter
+}
+
+var _ gosmartis.Observer = (*Observer)(nil)
+
+func New(opts Options) (*Observer, error) {
+	if opts.TracerProvider == nil {
+		opts.TracerProvider = otel.GetTracerProvider()
+	}
+
+	if opts.MeterProvider == nil {
+		opts.MeterProvider = otel.GetMeterProvider()
+	}
+
+	meter := opts.MeterProvider.Meter(instrumentationName)
+	o := &Observer{tracer: opts.TracerProvider.Tracer(instrumentationName)}
+
+	var err error
+
+	o.callDuration, err = meter.Float64Histogram("smartis.client.call.duration",
+		metric.WithUnit("s"), metric.WithDescription("Duration of Smartis client calls."))
+	if err != nil {
+		return nil, err
+	}
+
+	o.requestDuration, err = meter.Float64Histogram("smartis.client.request.duration",
+		metric.WithUnit("s"), metric.WithDescription("Duration of Smartis HTTP requests, including the response body."))
+	if err != nil {
+		return nil, err
+	}
+
+	o.requestSize, err = meter.Int64Histogram("smartis.client.request.body.size",
+		metric.WithUnit("By"), metric.WithDescription("Size of Smartis request bodies."))
+	if err != nil {
+		return nil, err
+	}
+
+	o.responseSize, err = meter.Int64Histogram("smartis.client.response.body.size",
+		metric.WithUnit("By"), metric.WithDescription("Size of Smartis response bodies."))
+	if err != nil {
+		return nil, err
+	}
+
+	o.limiterWait, err = meter.Float64Histogram("smartis.client.limiter.wait",
+		metric.WithUnit("s"), metric.WithDescription("Time spent waiting for Client.Limiter."))
+	if err != nil {
+		return nil, err
+	}
+
+	o.requests, err = meter.Int64Counter("smartis.client.requests",
+		metric.WithUnit("{request}"), metric.WithDescription("Smartis HTTP requests by endpoint and outcome."))
+	if err != nil {
+		return nil, err
+	}
+
+	o.rows, err = meter.Int64Counter("smartis.client.rows",
+		metric.WithUnit("{row}"), metric.WithDescription("Report rows and dictionary items decoded."))
+	if err != nil {
+		return nil, err
+	}
+
+	return o, nil
+}
+
+// StartCall implements gosmartis.Observer.
+func (o *Observer) StartCall(ctx context.Context, call gosmartis.Call) (context.Context, func(int, error)) {
+	start := time.Now()
+
+	attrs := []attribute.KeyValue{methodKey.String(call.Method)}
+
+	if call.Payload.Project != "" {
+		attrs = append(attrs, projectKey.String(call.Payload.Project))
+	}
+
+	if len(call.Payload.Metrics) > 0 {
+		attrs = append(attrs,
+			metricsKey.StringSlice(call.Payload.Metrics),
+			metricCountKey.Int(len(call.Payload.Metrics)))
+	}
+
+	if !call.Payload.DateTimeFrom.IsZero() {
+		attrs = append(attrs, dateFromKey.String(call.Payload.DateTimeFrom.Format(dateLayout)))
+	}
+
+	if !call.Payload.DateTimeTo.IsZero() {
+		attrs = append(attrs, dateToKey.String(call.Payload.DateTimeTo.Format(dateLayout)))
+	}
+
+	if call.IDs > 0 {
+		attrs = append(attrs, idsKey.Int(call.IDs))
+	}
+
+	ctx, span := o.tracer.Start(ctx, "smartis."+call.Method, trace.WithAttributes(attrs...))
+
+	return ctx, func(rows int, err error) {
+		span.SetAttributes(rowsKey.Int(rows))
+
+		failed := recordError(span, err)
+
+		metricAttrs := metric.WithAttributes(methodKey.String(call.Method), errorKey.Bool(failed))
+		o.callDuration.Record(ctx, time.Since(start).Seconds(), metricAttrs)
+		o.rows.Add(ctx, int64(rows), metric.WithAttributes(methodKey.String(call.Method)))
+
+		span.End()
+	}
+}
+
+// StartRequest implements gosmartis.Observer.
+func (o *Observer) StartRequest(ctx context.Context, req gosmartis.Request) (context.Context, func(gosmartis.RequestResult)) {
+	start := time.Now()
+
+	ctx, span := o.tracer.Start(ctx, "POST "+req.Endpoint,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			endpointKey.String(req.Endpoint),
+			requestBytesKey.Int(req.Bytes),
+		))
+
+	endpoint := endpointKey.String(req.Endpoint)
+	o.requestSize.Record(ctx, int64(req.Bytes), metric.WithAttributes(endpoint))
+
+	if req.Wait > 0 {
+		span.SetAttributes(waitKey.Float64(float64(req.Wait) / float64(time.Millisecond)))
+		o.limiterWait.Record(ctx, req.Wait.Seconds(), metric.WithAttributes(endpoint))
+	}
+
+	return ctx, func(result gosmartis.RequestResult) {
+		span.SetAttributes(responseBytesKey.Int64(result.Bytes))
+
+		if result.StatusCode != 0 {
+			span.SetAttributes(statusCodeKey.Int(result.StatusCode))
+		}
+
+		failed := recordError(span, result.Err)
+
+		attrs := metric.WithAttributes(endpoint, statusCodeKey.Int(result.StatusCode), errorKey.Bool(failed))
+		o.requestDuration.Record(ctx, time.Since(start).Seconds(), attrs)
+		o.responseSize.Record(ctx, result.Bytes, metric.WithAttributes(endpoint))
+		o.requests.Add(ctx, 1, attrs)
+
+		span.End()
+	}
+}
+
+// recordError marks the span as failed and reports whether err is a
+// failure. An empty report is not one.
+func recordError(span trace.Span, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, gosmartis.ErrNoReportData):
+		span.SetAttributes(noDataKey.Bool(true))
+
+		return false
+	default:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+
+		return true
+	}
+}
@@ -0,0 +1,323 @@
+package telemetry_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/zfullio/gosmartis"
+	"github.com/zfullio/gosmartis/telemetry"
+)
+
+// serverTransport sends the client's requests to a test server instead of
+// the Smartis API.
+type serverTransport struct {
+	server *url.URL
+}
+
+func (t serverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = t.server.Scheme
+	req.URL.Host = t.server.Host
+
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func newServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/reports/getReport"):
+			var body struct {
+				Project string `json:"project"`
+			}
+
+			_ = json.NewDecoder(r.Body).Decode(&body)
+
+			if body.Project == "empty" {
+				_, _ = w.Write([]byte(`{"reports": {}}`))
+
+				return
+			}
+
+			_, _ = w.Write([]byte(`{"reports": {
+				"leads": [{"day": "2024-01-01", "leads": 1}, {"day": "2024-01-02", "leads": 2}],
+				"visits": [{"day": "2024-01-01", "visits": 10}]
+			}}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+type setup struct {
+	client *gosmartis.Client
+	spans  *tracetest.InMemoryExporter
+	reader *sdkmetric.ManualReader
+}
+
+func newSetup(t *testing.T) setup {
+	t.Helper()
+
+	server := newServer(t)
+
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spans := tracetest.NewInMemoryExporter()
+	tracerProvider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans))
+	reader := sdkmetric.NewManualReader()
+	meterProvider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	obs, err := telemetry.New(telemetry.Options{TracerProvider: tracerProvider, MeterProvider: meterProvider})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := gosmartis.NewClient("key", "token", &http.Client{Transport: serverTransport{server: serverURL}})
+	client.Observer = obs
+
+	return setup{client: client, spans: spans, reader: reader}
+}
+
+func attrs(kvs []attribute.KeyValue) map[attribute.Key]attribute.Value {
+	result := make(map[attribute.Key]attribute.Value, len(kvs))
+	for _, kv := range kvs {
+		result[kv.Key] = kv.Value
+	}
+
+	return result
+}
+
+func spanNamed(t *testing.T, spans tracetest.SpanStubs, name string) tracetest.SpanStub {
+	t.Helper()
+
+	for _, span := range spans {
+		if span.Name == name {
+			return span
+		}
+	}
+
+	t.Fatalf("no span %q", name)
+
+	return tracetest.SpanStub{}
+}
+
+func TestGetReportSpans(t *testing.T) {
+	s := newSetup(t)
+
+	payload := gosmartis.Payload{
+		Project:      "object_1",
+		Metrics:      []string{"leads", "visits"},
+		DateTimeFrom: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		DateTimeTo:   time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+		GroupBy:      gosmartis.GroupByDay,
+	}
+
+	reports, err := s.client.GetReport(context.Background(), payload)
+	if err != nil || len(reports) != 2 {
+		t.Fatalf("GetReport = %v, %v", reports, err)
+	}
+
+	spans := s.spans.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("spans = %d, want a call and a request span", len(spans))
+	}
+
+	call := spanNamed(t, spans, "smartis.GetReport")
+	request := spanNamed(t, spans, "POST reports/getReport")
+
+	callAttrs := attrs(call.Attributes)
+
+	if callAttrs["smartis.method"].AsString() != "GetReport" ||
+		callAttrs["smartis.project"].AsString() != "object_1" ||
+		callAttrs["smartis.metrics.count"].AsInt64() != 2 ||
+		strings.Join(callAttrs["smartis.metrics"].AsStringSlice(), ",") != "leads,visits" ||
+		callAttrs["smartis.date_from"].AsString() != "2024-01-01" ||
+		callAttrs["smartis.date_to"].AsString() != "2024-01-31" ||
+		callAttrs["smartis.rows"].AsInt64() != 3 {
+		t.Errorf("call attributes = %v", call.Attributes)
+	}
+
+	if _, ok := callAttrs["smartis.retries"]; ok {
+		t.Error("call span has a retries attribute")
+	}
+
+	if call.Status.Code != codes.Unset {
+		t.Errorf("call status = %v", call.Status)
+	}
+
+	requestAttrs := attrs(request.Attributes)
+
+	if request.SpanKind != trace.SpanKindClient ||
+		request.Parent.SpanID() != call.SpanContext.SpanID() ||
+		requestAttrs["smartis.endpoint"].AsString() != "reports/getReport" ||
+		requestAttrs["http.response.status_code"].AsInt64() != http.StatusOK ||
+		requestAttrs["http.request.body.size"].AsInt64() == 0 ||
+		requestAttrs["http.response.body.size"].AsInt64() == 0 {
+		t.Errorf("request span = %v, kind %v, parent %v", request.Attributes, request.SpanKind, request.Parent.SpanID())
+	}
+}
+
+func TestFailedAndEmptyCalls(t *testing.T) {
+	s := newSetup(t)
+	ctx := context.Background()
+
+	_, err := s.client.GetProjects(ctx)
+	if err == nil {
+		t.Fatal("want a server error")
+	}
+
+	_, err = s.client.GetReport(ctx, gosmartis.Payload{Project: "empty"})
+	if !errors.Is(err, gosmartis.ErrNoReportData) {
+		t.Fatalf("empty report: %v", err)
+	}
+
+	spans := s.spans.GetSpans()
+
+	failed := spanNamed(t, spans, "smartis.GetProjects")
+	if failed.Status.Code != codes.Error || len(failed.Events) == 0 {
+		t.Errorf("failed call status = %v, events %v", failed.Status, failed.Events)
+	}
+
+	failedRequest := spanNamed(t, spans, "POST projects/get")
+	if attrs(failedRequest.Attributes)["http.response.status_code"].AsInt64() != http.StatusInternalServerError ||
+		failedRequest.Status.Code != codes.Error {
+		t.Errorf("failed request = %v, %v", failedRequest.Attributes, failedRequest.Status)
+	}
+
+	empty := spanNamed(t, spans, "smartis.GetReport")
+	if empty.Status.Code != codes.Unset || !attrs(empty.Attributes)["smartis.no_data"].AsBool() {
+		t.Errorf("empty report span = %v, %v", empty.Attributes, empty.Status)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	s := newSetup(t)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, err := s.client.GetReport(ctx, gosmartis.Payload{Project: "object_1"})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, _ = s.client.GetProjects(ctx)
+
+	var rm metricdata.ResourceMetrics
+
+	err := s.reader.Collect(ctx, &rm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metrics := make(map[string]metricdata.Metrics)
+
+	for _, scope := range rm.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			metrics[m.Name] = m
+		}
+	}
+
+	requests := sumPoints(t, metrics, "smartis.client.requests")
+	if requests[`reports/getReport 200 false`] != 2 || requests[`projects/get 500 true`] != 1 {
+		t.Errorf("requests = %v", requests)
+	}
+
+	rows := sumPoints(t, metrics, "smartis.client.rows")
+	if rows["GetReport"] != 6 || rows["GetProjects"] != 0 {
+		t.Errorf("rows = %v", rows)
+	}
+
+	calls, ok := metrics["smartis.client.call.duration"].Data.(metricdata.Histogram[float64])
+	if !ok {
+		t.Fatalf("call duration = %T", metrics["smartis.client.call.duration"].Data)
+	}
+
+	counts := make(map[string]uint64)
+	for _, point := range calls.DataPoints {
+		counts[pointKey(point.Attributes)] += point.Count
+	}
+
+	if counts["GetReport false"] != 2 || counts["GetProjects true"] != 1 {
+		t.Errorf("call durations = %v", counts)
+	}
+
+	for _, name := range []string{"smartis.client.request.duration", "smartis.client.request.body.size", "smartis.client.response.body.size"} {
+		if _, ok := metrics[name]; !ok {
+			t.Errorf("no %s", name)
+		}
+	}
+
+	if _, ok := metrics["smartis.client.limiter.wait"]; ok {
+		t.Error("limiter wait recorded without a limiter")
+	}
+}
+
+func TestNoObserver(t *testing.T) {
+	s := newSetup(t)
+	s.client.Observer = nil
+
+	_, err := s.client.GetReport(context.Background(), gosmartis.Payload{Project: "object_1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(s.spans.GetSpans()); n != 0 {
+		t.Errorf("spans without an observer = %d", n)
+	}
+}
+
+func sumPoints(t *testing.T, metrics map[string]metricdata.Metrics, name string) map[string]int64 {
+	t.Helper()
+
+	sum, ok := metrics[name].Data.(metricdata.Sum[int64])
+	if !ok {
+		t.Fatalf("%s = %T", name, metrics[name].Data)
+	}
+
+	result := make(map[string]int64)
+	for _, point := range sum.DataPoints {
+		result[pointKey(point.Attributes)] += point.Value
+	}
+
+	return result
+}
+
+// pointKey joins the method or endpoint, status code and error attributes
+// of a data point that has them.
+func pointKey(set attribute.Set) string {
+	var parts []string
+
+	for _, key := range []attribute.Key{"smartis.method", "smartis.endpoint", "http.response.status_code", "error"} {
+		if v, ok := set.Value(key); ok {
+			parts = append(parts, v.Emit())
+		}
+	}
+
+	return strings.Join(parts, " ")
+}